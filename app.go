@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/gobwas/glob"
 	"github.com/goccy/go-yaml"
 	"github.com/shirou/gopsutil/v3/process"
@@ -21,17 +22,31 @@ import (
 
 const logMaxLength = 20
 
+// Reasons for making a backup, other than the game changing the files while running
+const (
+	backupReasonManual       = "manual"
+	backupReasonSweep        = "sweep"
+	backupReasonGameStopped  = "stopped"
+	backupReasonSessionStart = "session-start"
+)
+
 func newConfig() *Config {
 	return &Config{
 		DisabledRules: []string{},
 		Backups: &BackupConfig{
-			KeepSaves:    250,
-			MaxMBPerGame: 1024,
+			KeepSaves:            250,
+			KeepConfigs:          20,
+			MaxMBPerGame:         1024,
+			KeepSessionSnapshots: 3,
 		},
 		Compaction: &CompactionConfig{
 			KeepSaves:        5,
 			CompactAfterDays: 180,
 		},
+		Sweeps: &SweepConfig{
+			IntervalMinutes: 60,
+		},
+		Rules:            map[string]*RuleConfig{},
 		RulesLastUpdated: time.Time{},
 		RulesAutoUpdate:  true,
 	}
@@ -41,14 +56,26 @@ func newConfig() *Config {
 func newApp() *App {
 	exit := make(chan bool)
 	a := &App{
-		Config:         newConfig(),
-		Rules:          map[string]ActiveRule{},
-		ActiveMonitors: []Monitor{},
-		ProcessList:    []string{},
-		BasePath:       getBasePath(),
-		Errors:         []string{},
-		Events:         []string{},
-		exit:           exit,
+		Config:           newConfig(),
+		Rules:            map[string]ActiveRule{},
+		Backups:          map[string][]BackupMetadata{},
+		Sessions:         map[string][]PlaySession{},
+		ActiveMonitors:   []Monitor{},
+		ProcessList:      []ProcessInfo{},
+		BasePath:         getBasePath(),
+		Errors:           []string{},
+		Events:           []string{},
+		exit:             exit,
+		watchedDirs:      map[string]bool{},
+		polledMonitors:   []Monitor{},
+		pendingChanges:   map[string]bool{},
+		activeSessions:   map[string]string{},
+		wrappedRules:     map[string]bool{},
+		wrapperRequests:  make(chan wrapperRequest),
+		monitorRequests:  make(chan monitorRequest),
+		monitorStopped:   make(chan struct{}),
+		runningSteamApps: map[string]bool{},
+		pauseState:       PauseState{Rules: map[string]*time.Time{}},
 	}
 
 	return a
@@ -103,7 +130,7 @@ func (a *App) LoadConfig() {
 		a.ReportError(fmt.Errorf("error parsing config from %s", configPath))
 	}
 
-	wailsRuntime.EventsEmit(a.ctx, "configUpdated", a.Config)
+	a.emit("configUpdated", a.Config)
 }
 
 // SaveConfig saves the application configuration
@@ -126,35 +153,169 @@ func (a *App) SaveConfig() {
 	}
 }
 
+// expandFilePatterns expands the savegame or config patterns of the rule
+func (a *App) expandFilePatterns(rule ActiveRule, patterns []string, winePrefixes []WinePrefix) []string {
+	result := []string{}
+	for _, v := range patterns {
+		if rule.ViaWine {
+			// Look for the files in all the Wine and Proton prefixes
+			wine, err := winePatterns(v, winePrefixes, a.lookupRuleVariable)
+			if err != nil {
+				a.reportPatternError(rule, v, err)
+				continue
+			}
+			result = append(result, wine...)
+			continue
+		}
+
+		// Expand ${HOME}, ${STEAM_ROOT} etc., and look for the files of the Flatpak and Snap
+		// versions of the game too
+		expanded, err := a.expandRuleVariables(v)
+		if err != nil {
+			a.reportPatternError(rule, v, err)
+			continue
+		}
+		result = append(result, expanded)
+		result = append(result, a.sandboxPatterns(rule.Platform, v)...)
+	}
+
+	return result
+}
+
 func (a *App) preprocessRules(rules map[string]ActiveRule) map[string]ActiveRule {
+	// Look for Steam and the other launchers again in case e.g. new games were installed
+	a.steam = a.findSteam()
+	a.installedGames = a.findInstalledGames()
+	a.sandboxes = findSandboxes()
+	if a.gameDirs == nil {
+		a.gameDirs = a.loadGameDirs()
+	}
+
+	var winePrefixes []WinePrefix
+	for _, rule := range rules {
+		if rule.ViaWine {
+			winePrefixes = findWinePrefixes(a.steam, a.installedGames)
+			break
+		}
+	}
+
 	newRules := map[string]ActiveRule{}
 	for key, rule := range rules {
-		// Expand ${HOME} etc. in the "Savegames" list
-		savegames := []string{}
-		for _, v := range rule.Platform.Savegames {
-			savegames = append(savegames, os.ExpandEnv(v))
+		platform := rule.Platform
+		newRule := ActiveRule{
+			RuleFilename:       rule.RuleFilename,
+			Name:               rule.Name,
+			Issues:             rule.Issues,
+			Platform:           platform,
+			ViaWine:            rule.ViaWine,
+			Disabled:           rule.Disabled,
+			savegameTemplates:  a.expandFilePatterns(rule, rule.Platform.Savegames, winePrefixes),
+			configTemplates:    a.expandFilePatterns(rule, rule.Platform.Configs, winePrefixes),
+			thumbnailTemplates: a.thumbnailPatterns(rule, winePrefixes),
 		}
+		newRule.excludeTemplates = a.excludePatterns(newRule, winePrefixes)
+		newRule.saveLabels = a.compileSaveLabels(newRule)
 
-		executableGlob, err := glob.Compile(rule.Platform.Executable)
-		if err != nil {
-			a.ReportError(fmt.Errorf("failed to parse pattern %s from %s.yaml", rule.Platform.Executable, rule.RuleFilename))
+		// Any of the executables, command line, or process name can detect the game
+		executables := platform.Executables
+		if platform.Executable != "" {
+			executables = append([]string{platform.Executable}, executables...)
+		}
+
+		valid := true
+		for _, pattern := range executables {
+			expanded, err := a.expandProcessPattern(pattern)
+			if err != nil {
+				a.reportPatternError(rule, pattern, err)
+				valid = false
+				continue
+			}
+			pattern = expanded
+			if rule.ViaWine {
+				// The process is Wine, the Windows executable is in the command line
+				for _, winePattern := range wineExecutablePattern(pattern) {
+					valid = a.addProcessMatcher(&newRule, matchWineExecutable, winePattern) && valid
+				}
+				continue
+			}
+
+			valid = a.addProcessMatcher(&newRule, matchExecutable, pattern) && valid
+		}
+		valid = a.addProcessMatcher(&newRule, matchCmdline, platform.Cmdline) && valid
+		valid = a.addProcessMatcher(&newRule, matchProcessName, platform.ProcessName) && valid
+		if !valid {
 			continue
 		}
 
-		newRules[key] = ActiveRule{
-			RuleFilename: rule.RuleFilename,
-			Name:         rule.Name,
-			Issues:       rule.Issues,
-			Platform: RulePlatform{
-				Executable: rule.Platform.Executable,
-				Savegames:  savegames,
-			},
-			executableGlob: executableGlob,
+		for _, pattern := range platform.ExcludeProcesses {
+			excludeGlob, err := a.compileProcessPattern(rule, pattern)
+			if err != nil {
+				continue
+			}
+			newRule.excludeProcessGlobs = append(newRule.excludeProcessGlobs, excludeGlob)
+		}
+
+		if len(newRule.processMatchers) == 0 && platform.SteamAppID == "" {
+			a.ReportError(fmt.Errorf("%s.yaml has no executable, cmdline, process_name, or steam_appid to detect the game with", rule.RuleFilename))
+			continue
 		}
+
+		newRule.Installed = a.findInstalledGame(newRule)
+
+		// Until the game is detected, ${match.name} matches anything and ${GAME_DIR} is where the
+		// game was last seen
+		newRule.resolveSavegames(a.knownGameDir(newRule))
+
+		newRules[key] = newRule
 	}
 	return newRules
 }
 
+// addProcessMatcher adds a matcher for the pattern to the rule, and tells if the pattern was valid
+func (a *App) addProcessMatcher(rule *ActiveRule, kind string, pattern string) bool {
+	compiled, err := a.compileProcessPattern(*rule, captureGlob(pattern))
+	if err != nil {
+		return false
+	}
+
+	if compiled == nil {
+		return true
+	}
+
+	matcher := processMatcher{
+		kind:    kind,
+		pattern: pattern,
+		glob:    compiled,
+	}
+
+	if hasCaptures(pattern) {
+		matcher.captures, err = captureRegexpFor(pattern, kind == matchWineExecutable)
+		if err != nil {
+			a.ReportError(fmt.Errorf("failed to parse pattern %s from %s.yaml: %w", pattern, rule.RuleFilename, err))
+			return false
+		}
+	}
+
+	rule.processMatchers = append(rule.processMatchers, matcher)
+
+	return true
+}
+
+// compileProcessPattern compiles a pattern for matching processes, an empty pattern gives a nil glob
+func (a *App) compileProcessPattern(rule ActiveRule, pattern string) (glob.Glob, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	compiled, err := glob.Compile(pattern)
+	if err != nil {
+		a.ReportError(fmt.Errorf("failed to parse pattern %s from %s.yaml", pattern, rule.RuleFilename))
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
 // LoadRules loads our rules/*.yaml configuration files
 func (a *App) LoadRules() {
 	activeRules := a.loadRules()
@@ -167,10 +328,85 @@ func (a *App) LoadRules() {
 	}
 
 	a.Rules = a.preprocessRules(activeRules)
-	wailsRuntime.EventsEmit(a.ctx, "rulesUpdated", a.Rules)
+
+	// Make sure CheckRules reads the backups from disk again
+	a.Backups = map[string][]BackupMetadata{}
+	a.emit("rulesUpdated", a.Rules)
 	a.AddEvent(fmt.Sprintf("Loaded %d rules", len(a.Rules)))
 }
 
+// isRuleDisabled checks if the rule is in disabled_rules, with or without the .yaml extension
+func (a *App) isRuleDisabled(ruleFilename string) bool {
+	for _, disabled := range a.Config.DisabledRules {
+		if strings.TrimSuffix(disabled, ".yaml") == ruleFilename {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableRule starts monitoring the game again, and removes it from disabled_rules in the config
+func (a *App) EnableRule(ruleFilename string) bool {
+	ok := false
+	a.onMonitor(func() {
+		ok = a.setRuleDisabled(ruleFilename, false)
+	})
+	return ok
+}
+
+// DisableRule stops monitoring the game, and adds it to disabled_rules in the config
+func (a *App) DisableRule(ruleFilename string) bool {
+	ok := false
+	a.onMonitor(func() {
+		ok = a.setRuleDisabled(ruleFilename, true)
+	})
+	return ok
+}
+
+// setRuleDisabled starts or stops monitoring the game right away, without reloading the rules, and
+// updates disabled_rules in the config
+func (a *App) setRuleDisabled(ruleFilename string, disabled bool) bool {
+	rule, ok := a.Rules[ruleFilename]
+	if !ok {
+		a.ReportError(fmt.Errorf("tried to enable or disable %s but there is no such rule", ruleFilename))
+		return false
+	}
+
+	disabledRules := []string{}
+	for _, disabledRule := range a.Config.DisabledRules {
+		if strings.TrimSuffix(disabledRule, ".yaml") != ruleFilename {
+			disabledRules = append(disabledRules, disabledRule)
+		}
+	}
+	if disabled {
+		disabledRules = append(disabledRules, fmt.Sprintf("%s.yaml", ruleFilename))
+	}
+	a.Config.DisabledRules = disabledRules
+	a.SaveConfig()
+	a.emit("configUpdated", a.Config)
+
+	rule.Disabled = disabled
+	a.Rules[ruleFilename] = rule
+	a.emit("rulesUpdated", a.Rules)
+
+	if disabled {
+		a.AddEvent(fmt.Sprintf("Disabled %s", rule.Name))
+
+		// Stop monitoring, without taking the backups as if the game had stopped
+		if _, running := a.ActiveRules[ruleFilename]; running {
+			delete(a.ActiveRules, ruleFilename)
+			a.endSession(rule)
+		}
+	} else {
+		a.AddEvent(fmt.Sprintf("Enabled %s", rule.Name))
+	}
+
+	// Start or stop watching the savegames of the running game
+	a.CheckRules()
+
+	return true
+}
+
 func (a *App) loadRules() map[string]ActiveRule {
 	platform := getPlatform()
 
@@ -186,21 +422,21 @@ func (a *App) loadRules() map[string]ActiveRule {
 
 		name := strings.TrimSuffix(filepath.Base(rulesFile), ".yaml")
 
-		contents, err := os.ReadFile(rulesFile)
+		err := a.parseRuleFile(rulesFile, rule)
 		if err != nil {
 			a.ReportError(err)
 			a.ReportError(fmt.Errorf("error parsing rules from %s", rulesFile))
 			continue
 		}
 
-		err = yaml.Unmarshal(contents, rule)
-		if err != nil {
-			a.ReportError(err)
-			a.ReportError(fmt.Errorf("error parsing rules from %s", rulesFile))
-			continue
+		// On Linux we can use the Windows rules for games running via Wine or Proton
+		viaWine := false
+		rulePlatform, ok := rule.Platforms[platform]
+		if !ok && platform == "linux" {
+			rulePlatform, ok = rule.Platforms["windows"]
+			viaWine = ok
 		}
 
-		rulePlatform, ok := rule.Platforms[platform]
 		if !ok {
 			a.ReportError(fmt.Errorf("%s does not support %s, skipping", rulesFile, platform))
 			continue
@@ -211,6 +447,8 @@ func (a *App) loadRules() map[string]ActiveRule {
 			Issues:       rule.Issues,
 			Name:         rule.Name,
 			Platform:     rulePlatform,
+			ViaWine:      viaWine,
+			Disabled:     a.isRuleDisabled(name),
 		}
 	}
 
@@ -223,36 +461,158 @@ func (a *App) DownloadRules() {
 	a.AddEvent("Should download rules off of github.com/cocreators-ee/baacup-rules")
 }
 
+// monitorRequest is work from the UI that has to run on the monitor goroutine, which owns the rules,
+// backups, and sessions
+type monitorRequest struct {
+	run  func()
+	done chan bool
+}
+
+// onMonitor runs the function on the monitor goroutine and waits for it to finish. Without the GUI
+// there is no monitor goroutine, so it runs right away.
+func (a *App) onMonitor(run func()) {
+	if a.headless {
+		run()
+		return
+	}
+
+	req := monitorRequest{
+		run:  run,
+		done: make(chan bool, 1),
+	}
+
+	select {
+	case a.monitorRequests <- req:
+		<-req.done
+	case <-a.monitorStopped:
+	}
+}
+
 func (a *App) runMonitor() {
 	pollMonitors := time.NewTicker(time.Second)
 	pollRules := time.NewTicker(time.Second * 15)
+	pollSweeps := time.NewTicker(time.Minute)
+	settled := time.NewTimer(watchSettleTime)
+	settled.Stop()
+
+	// Without a watcher these stay nil and are never selected
+	var watchEvents chan fsnotify.Event
+	var watchErrors chan error
+	if a.watcher != nil {
+		watchEvents = a.watcher.Events
+		watchErrors = a.watcher.Errors
+	}
+
+	// Requests from the UI don't wait for a monitor that is no longer there
+	defer close(a.monitorStopped)
 
 	for {
 		select {
 		case <-a.exit:
 			pollMonitors.Stop()
 			pollRules.Stop()
+			pollSweeps.Stop()
+			settled.Stop()
+			a.stopWatcher()
 			return
 
+		case event := <-watchEvents:
+			a.handleWatchEvent(event)
+			settled.Reset(watchSettleTime)
+
+		case err := <-watchErrors:
+			a.ReportError(err)
+
+		case <-settled.C:
+			a.checkPendingChanges()
+
+		case req := <-a.wrapperRequests:
+			req.done <- a.handleWrapperRequest(req)
+
+		case req := <-a.monitorRequests:
+			req.run()
+			req.done <- true
+
 		case <-pollMonitors.C:
+			a.checkPauses()
 			a.checkMonitors()
 
 		case <-pollRules.C:
 			a.CheckRules()
+
+		case <-pollSweeps.C:
+			a.checkSweep()
 		}
 	}
 }
 
 func (a *App) checkMonitors() {
-	for _, monitor := range a.ActiveMonitors {
-		newFiles := a.findNewFiles(monitor.Path, a.Backups[monitor.RuleFilename])
+	for _, monitor := range a.polledMonitors {
+		if a.isPaused(monitor.RuleFilename) {
+			continue
+		}
+
+		newFiles := a.findNewFiles(monitor.Path, monitor.Exclude, a.Backups[monitor.RuleFilename])
 		for _, newFile := range newFiles {
-			a.backupFile(monitor.RuleFilename, newFile)
+			a.backupFile(monitor.RuleFilename, newFile, "")
 		}
 	}
 }
 
-func (a *App) findNewFiles(filePath string, backups []BackupMetadata) []string {
+func (a *App) checkSweep() {
+	interval := time.Duration(a.Config.Sweeps.IntervalMinutes) * time.Minute
+	if interval <= 0 || time.Since(a.lastSweep) < interval {
+		return
+	}
+
+	a.sweep()
+}
+
+// Sweep backs up changes to savegames of games that are not running, e.g. by cloud sync clients
+func (a *App) Sweep() int {
+	backedUp := 0
+	a.onMonitor(func() {
+		backedUp = a.sweep()
+	})
+	return backedUp
+}
+
+func (a *App) sweep() int {
+	a.lastSweep = time.Now()
+
+	backedUp := 0
+	for ruleFilename, rule := range a.Rules {
+		// Running games are already being monitored
+		if _, running := a.ActiveRules[ruleFilename]; running || rule.Disabled || a.isPaused(ruleFilename) {
+			continue
+		}
+
+		backedUp += a.backupChangedFiles(ruleFilename, rule, backupReasonSweep)
+	}
+
+	if backedUp > 0 {
+		a.AddEvent(fmt.Sprintf("Backed up %d files changed outside of play sessions", backedUp))
+	}
+
+	return backedUp
+}
+
+// backupChangedFiles backs up all the savegames and configs of the rule that have changed since the last backup
+func (a *App) backupChangedFiles(ruleFilename string, rule ActiveRule, reason string) int {
+	backedUp := 0
+	for _, savePath := range rule.filePatterns() {
+		newFiles := a.findNewFiles(savePath, rule.Platform.Exclude, a.Backups[ruleFilename])
+		for _, newFile := range newFiles {
+			if a.backupFile(ruleFilename, newFile, reason) {
+				backedUp++
+			}
+		}
+	}
+
+	return backedUp
+}
+
+func (a *App) findNewFiles(filePath string, excludes []string, backups []BackupMetadata) []string {
 	var newFiles []string
 
 	files, err := filepath.Glob(filePath)
@@ -262,16 +622,11 @@ func (a *App) findNewFiles(filePath string, backups []BackupMetadata) []string {
 	}
 
 	for _, f := range files {
-		needsBackup := true
-		metas := listMetadataBySource(backups, f)
-		for _, meta := range metas {
-			if !a.fileModifiedAfter(f, meta.LastModified) {
-				needsBackup = false
-			}
+		if isExcluded(f, filePath, excludes) {
+			continue
 		}
 
-		if needsBackup {
-			a.AddEvent(fmt.Sprintf("%s needs backup", f))
+		if a.needsBackup(f, backups) {
 			newFiles = append(newFiles, f)
 		}
 	}
@@ -279,6 +634,18 @@ func (a *App) findNewFiles(filePath string, backups []BackupMetadata) []string {
 	return newFiles
 }
 
+func (a *App) needsBackup(file string, backups []BackupMetadata) bool {
+	metas := listMetadataBySource(backups, file)
+	for _, meta := range metas {
+		if !a.fileModifiedAfter(file, meta.LastModified) {
+			return false
+		}
+	}
+
+	a.AddEvent(fmt.Sprintf("%s needs backup", file))
+	return true
+}
+
 func (a *App) fileModifiedAfter(file string, lastModified time.Time) bool {
 	s, err := os.Lstat(file)
 	if err != nil {
@@ -299,41 +666,122 @@ func listMetadataBySource(backups []BackupMetadata, source string) []BackupMetad
 	return matches
 }
 
-func (a *App) backupFile(ruleFilename string, sourcePath string) {
+func (a *App) backupFile(ruleFilename string, sourcePath string, reason string) bool {
 	rule := a.Rules[ruleFilename]
 
 	stat, err := os.Stat(sourcePath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-
-		if os.IsPermission(err) {
+		if !os.IsNotExist(err) {
 			a.ReportError(err)
-			return
 		}
+		return false
 	}
 
 	meta := BackupMetadata{
 		Filename:     "",
 		Source:       sourcePath,
+		Category:     rule.fileCategory(sourcePath),
+		Label:        rule.saveLabel(sourcePath),
 		BackupTime:   time.Now(),
 		LastModified: stat.ModTime(),
+		Reason:       reason,
 	}
 
 	meta, err = a.makeBackup(ruleFilename, meta)
 	if err != nil {
 		a.ReportError(err)
-		return
+		return false
 	}
 
 	// Now that we've successfully backed it up, load the metadata in memory
 	a.Backups[ruleFilename] = append(a.Backups[ruleFilename], meta)
+	a.addBackupToSession(ruleFilename, meta.Filename)
 
 	a.limitBackupSize(ruleFilename)
 
-	wailsRuntime.EventsEmit(a.ctx, "backupsUpdated", a.Backups)
-	a.AddEvent(fmt.Sprintf("Backed up %s savegame %s", rule.Name, filepath.Base(sourcePath)))
+	a.emit("backupsUpdated", a.Backups)
+	a.AddEvent(fmt.Sprintf("Backed up %s %s %s", rule.Name, meta.category(), filepath.Base(sourcePath)))
+
+	return true
+}
+
+// BackupNow backs up all the savegames of a game, whether it's running or not, and whether the
+// files have changed since the last backup or not
+func (a *App) BackupNow(ruleFilename string) int {
+	backedUp := 0
+	a.onMonitor(func() {
+		backedUp = a.backupNow(ruleFilename)
+	})
+	return backedUp
+}
+
+func (a *App) backupNow(ruleFilename string) int {
+	rule, ok := a.Rules[ruleFilename]
+	if !ok {
+		a.ReportError(fmt.Errorf("tried to back up %s but there is no such rule", ruleFilename))
+		return 0
+	}
+
+	if _, ok := a.Backups[ruleFilename]; !ok {
+		a.Backups[ruleFilename] = a.findBackupMetadata(ruleFilename)
+	}
+
+	backedUp := a.backupAllFiles(ruleFilename, rule, backupReasonManual)
+	a.AddEvent(fmt.Sprintf("Backed up %d files of %s", backedUp, rule.Name))
+
+	return backedUp
+}
+
+// backupAllFiles backs up all the savegames and configs of the rule, even if they haven't changed
+func (a *App) backupAllFiles(ruleFilename string, rule ActiveRule, reason string) int {
+	backedUp := 0
+	for _, savePath := range rule.filePatterns() {
+		files, err := filepath.Glob(savePath)
+		if err != nil {
+			a.ReportError(err)
+			continue
+		}
+
+		for _, f := range files {
+			if isExcluded(f, savePath, rule.Platform.Exclude) {
+				continue
+			}
+
+			if a.backupFile(ruleFilename, f, reason) {
+				backedUp++
+			}
+		}
+	}
+
+	return backedUp
+}
+
+// BackupAll backs up the savegames of all the games we have rules for
+func (a *App) BackupAll() int {
+	backedUp := 0
+	a.onMonitor(func() {
+		backedUp = a.backupAll()
+	})
+	return backedUp
+}
+
+func (a *App) backupAll() int {
+	ruleFilenames := []string{}
+	for ruleFilename, rule := range a.Rules {
+		if !rule.Disabled {
+			ruleFilenames = append(ruleFilenames, ruleFilename)
+		}
+	}
+	sort.Strings(ruleFilenames)
+
+	backedUp := 0
+	for _, ruleFilename := range ruleFilenames {
+		backedUp += a.backupNow(ruleFilename)
+	}
+
+	a.AddEvent(fmt.Sprintf("Backed up %d files of all games", backedUp))
+
+	return backedUp
 }
 
 func (a *App) limitBackupSize(ruleFilename string) {
@@ -344,11 +792,21 @@ func (a *App) limitBackupSize(ruleFilename string) {
 		return l.BackupTime.After(r.BackupTime)
 	})
 
-	// Limit backups to max length
+	// Limit backups to max length, recent session start snapshots don't count towards it. Configs
+	// are kept separately, so changing the savegames often doesn't push them out.
 	reported := false
-	if len(a.Backups[ruleFilename]) > a.Config.Backups.KeepSaves {
-		extra := a.Backups[ruleFilename][a.Config.Backups.KeepSaves:]
-		for _, meta := range extra {
+	keep := map[string]int{
+		backupCategorySavegame: a.Config.Backups.KeepSaves,
+		backupCategoryConfig:   a.Config.Backups.KeepConfigs,
+	}
+	kept := map[string]int{}
+	for _, meta := range a.Backups[ruleFilename] {
+		if a.isProtectedSnapshot(ruleFilename, meta) {
+			continue
+		}
+
+		kept[meta.category()]++
+		if kept[meta.category()] > keep[meta.category()] {
 			a.DeleteBackup(ruleFilename, meta.Filename)
 		}
 	}
@@ -370,6 +828,23 @@ func (a *App) limitBackupSize(ruleFilename string) {
 	}
 }
 
+// isProtectedSnapshot checks if the backup is a session start snapshot with too few newer sessions
+// to be pruned yet
+func (a *App) isProtectedSnapshot(ruleFilename string, meta BackupMetadata) bool {
+	if meta.Reason != backupReasonSessionStart {
+		return false
+	}
+
+	newerSessions := 0
+	for _, session := range a.Sessions[ruleFilename] {
+		if session.Start.After(meta.BackupTime) {
+			newerSessions++
+		}
+	}
+
+	return newerSessions < a.Config.Backups.KeepSessionSnapshots
+}
+
 // DeleteBackup deletes a specific backup
 func (a *App) DeleteBackup(ruleFilename string, filename string) {
 	// Figure out filenames
@@ -387,6 +862,8 @@ func (a *App) DeleteBackup(ruleFilename string, filename string) {
 	for _, meta := range a.Backups[ruleFilename] {
 		if meta.Filename != filename {
 			backups = append(backups, meta)
+		} else if meta.Thumbnail != "" {
+			a.tryDeleteFile(filepath.Join(backupPath, meta.Thumbnail))
 		}
 	}
 	a.Backups[ruleFilename] = backups
@@ -411,9 +888,10 @@ func (a *App) makeBackup(ruleFilename string, meta BackupMetadata) (BackupMetada
 	ext := filepath.Ext(meta.Source)
 	baseNoExt := strings.TrimSuffix(filepath.Base(meta.Source), ext)
 	timestamp := meta.BackupTime.Format("2006-01-02T150405.000")
+	backupBase := uniqueBackupBase(backupPath, fmt.Sprintf("%s-%s", baseNoExt, timestamp), ext)
 
-	backupFilename := fmt.Sprintf("%s-%s%s", baseNoExt, timestamp, ext)
-	metaFilename := fmt.Sprintf("%s-%s.baacup.yaml", baseNoExt, timestamp)
+	backupFilename := fmt.Sprintf("%s%s", backupBase, ext)
+	metaFilename := fmt.Sprintf("%s.baacup.yaml", backupBase)
 
 	// Make sure we update the metadata with this new filename
 	meta.Filename = backupFilename
@@ -424,6 +902,17 @@ func (a *App) makeBackup(ruleFilename string, meta BackupMetadata) (BackupMetada
 		return meta, err
 	}
 
+	// Make the copy of the file
+	backupDst := filepath.Join(backupPath, backupFilename)
+	err = copyFile(meta.Source, backupDst)
+	if err != nil {
+		return meta, err
+	}
+
+	// Inspect the copy so the details match exactly what we stored
+	meta.Inspector, meta.Details = a.inspectSave(backupDst)
+	meta.Thumbnail = a.backupThumbnail(a.Rules[ruleFilename], meta.Source, backupDst, backupBase)
+
 	// Write metadata file
 	metaFile := filepath.Join(backupPath, metaFilename)
 	data, err := yaml.Marshal(meta)
@@ -438,11 +927,22 @@ func (a *App) makeBackup(ruleFilename string, meta BackupMetadata) (BackupMetada
 		return meta, err
 	}
 
-	// Make the copy of the file
-	backupDst := filepath.Join(backupPath, backupFilename)
-	err = copyFile(meta.Source, backupDst)
+	return meta, nil
+}
 
-	return meta, err
+// uniqueBackupBase adds a counter to the name of the backup if it's already taken. Files with the same
+// name in different folders, e.g. saves/*/level.dat, are often backed up within the same millisecond.
+func uniqueBackupBase(backupPath string, base string, ext string) string {
+	unique := base
+	for i := 2; ; i++ {
+		_, err := os.Lstat(filepath.Join(backupPath, unique+ext))
+		_, metaErr := os.Lstat(filepath.Join(backupPath, unique+".baacup.yaml"))
+		if err != nil && metaErr != nil {
+			return unique
+		}
+
+		unique = fmt.Sprintf("%s-%d", base, i)
+	}
 }
 
 func copyFile(src, dst string) error {
@@ -488,6 +988,14 @@ func copyFile(src, dst string) error {
 
 // RestoreBackup restores a selected backup
 func (a *App) RestoreBackup(ruleFilename string, filename string) bool {
+	success := false
+	a.onMonitor(func() {
+		success = a.restoreBackup(ruleFilename, filename)
+	})
+	return success
+}
+
+func (a *App) restoreBackup(ruleFilename string, filename string) bool {
 	metadata := a.findBackupMetadataForRestore(ruleFilename, filename)
 	if metadata.Source == "" {
 		// For some reason couldn't find the metadata
@@ -514,15 +1022,6 @@ func (a *App) RestoreBackup(ruleFilename string, filename string) bool {
 	return true
 }
 
-func existsInList(items []string, item string) bool {
-	for _, val := range items {
-		if val == item {
-			return true
-		}
-	}
-	return false
-}
-
 func (a *App) pollProcessList() {
 	procs, err := process.Processes()
 	if err != nil {
@@ -530,20 +1029,26 @@ func (a *App) pollProcessList() {
 		return
 	}
 
-	processList := []string{}
+	processList := []ProcessInfo{}
+	seen := map[ProcessInfo]bool{}
 	for _, proc := range procs {
-		exe, err := proc.Exe()
-		if err != nil {
-			if !os.IsPermission(err) && !os.IsNotExist(err) {
-				a.ReportError(err)
-			}
-			continue
+		// We may not be allowed to see everything about all processes, use whatever we can get
+		info := ProcessInfo{}
+
+		info.Exe, err = proc.Exe()
+		if err != nil && !os.IsPermission(err) && !os.IsNotExist(err) {
+			a.ReportError(err)
 		}
 
-		if !existsInList(processList, exe) {
-			processList = append(processList, exe)
-			// wailsRuntime.LogPrint(a.ctx, exe)
+		info.Name, _ = proc.Name()
+		info.Cmdline, _ = proc.Cmdline()
+
+		if info == (ProcessInfo{}) || seen[info] {
+			continue
 		}
+
+		seen[info] = true
+		processList = append(processList, info)
 	}
 
 	a.ProcessList = processList
@@ -552,39 +1057,118 @@ func (a *App) pollProcessList() {
 // CheckRules checks which of the rules are currently matching running processes and we should monitor for
 func (a *App) CheckRules() {
 	a.pollProcessList()
+	a.pollSteamApps()
 
 	// Add all savegame paths for all running games to monitoring
 	var monitors []Monitor
-	backups := map[string][]BackupMetadata{}
 	rules := map[string]ActiveRule{}
 
 	for key, rule := range a.Rules {
-		backups[rule.RuleFilename] = a.findBackupMetadata(rule.RuleFilename)
-		if a.IsRunning(rule.executableGlob) {
+		// We keep track of the backups we make, so they only need to be read once
+		if _, ok := a.Backups[rule.RuleFilename]; !ok {
+			a.Backups[rule.RuleFilename] = a.findBackupMetadata(rule.RuleFilename)
+		}
+
+		if _, ok := a.Sessions[rule.RuleFilename]; !ok {
+			a.Sessions[rule.RuleFilename] = a.loadSessions(rule.RuleFilename)
+		}
+
+		// The user doesn't want the game backed up, even if it's running
+		if rule.Disabled {
+			continue
+		}
+
+		matcher, proc, running := a.findGameProcess(rule)
+		if !running {
+			matcher, proc, running = a.findSteamGame(rule)
+		}
+		if a.wrappedRules[key] && !running {
+			matcher = processMatcher{kind: matchWrapper}
+			running = true
+		}
+
+		if running {
+			rule.DetectedBy = matcher.String()
+			rule.DetectedProcess = proc.Exe
+			if rule.DetectedProcess == "" {
+				rule.DetectedProcess = proc.Name
+			}
+
+			// Only back up the saves of e.g. the ROM the emulator is running
+			rule.Captures = matcher.capture(proc)
+
+			gameDir := detectedGameDir(rule, matcher, proc)
+			if gameDir == "" {
+				gameDir = a.knownGameDir(rule)
+			}
+			rule.resolveSavegames(gameDir)
+			a.rememberGameDir(rule)
+
 			var ruleMonitors []Monitor
-			for _, savePath := range rule.Platform.Savegames {
+			for _, savePath := range rule.filePatterns() {
 				ruleMonitors = append(ruleMonitors, Monitor{
 					Path:         savePath,
+					Exclude:      rule.Platform.Exclude,
 					RuleFilename: rule.RuleFilename,
 				})
 			}
 
 			monitors = append(monitors, ruleMonitors...)
 			rules[key] = rule
-
-			a.AddEvent(fmt.Sprintf("Detected %s as running", rule.Name))
 		}
 	}
 
-	// TODO: Check for things no longer running
-
-	a.Backups = backups
+	previousRules := a.ActiveRules
 	a.ActiveMonitors = monitors
 	a.ActiveRules = rules
+	a.updateWatches()
+
+	for key, rule := range rules {
+		if _, wasRunning := previousRules[key]; !wasRunning {
+			a.gameStarted(rule)
+		}
+	}
+
+	for key, rule := range previousRules {
+		if _, isRunning := rules[key]; !isRunning {
+			a.gameStopped(rule)
+		}
+	}
 
-	wailsRuntime.EventsEmit(a.ctx, "backupsUpdated", a.Backups)
-	wailsRuntime.EventsEmit(a.ctx, "activeMonitorsUpdated", a.ActiveMonitors)
-	wailsRuntime.EventsEmit(a.ctx, "activeRulesUpdated", a.ActiveRules)
+	a.emit("backupsUpdated", a.Backups)
+	a.emit("activeMonitorsUpdated", a.ActiveMonitors)
+	a.emit("activeRulesUpdated", a.ActiveRules)
+}
+
+func (a *App) gameStarted(rule ActiveRule) {
+	a.AddEvent(fmt.Sprintf("Detected %s as running by %s", rule.Name, rule.DetectedBy))
+	a.startSession(rule)
+	a.emit("gameStarted", rule)
+
+	if a.isPaused(rule.RuleFilename) {
+		return
+	}
+
+	// The savegames are in their last known good state right before the game changes anything
+	backedUp := a.backupAllFiles(rule.RuleFilename, rule, backupReasonSessionStart)
+	if backedUp > 0 {
+		a.AddEvent(fmt.Sprintf("Took a snapshot of %d files of %s at the start of the session", backedUp, rule.Name))
+	}
+}
+
+func (a *App) gameStopped(rule ActiveRule) {
+	a.AddEvent(fmt.Sprintf("Detected %s is no longer running", rule.Name))
+	a.emit("gameStopped", rule)
+
+	// Many games write their last save when exiting, make sure we didn't miss it
+	if !a.isPaused(rule.RuleFilename) {
+		backedUp := a.backupChangedFiles(rule.RuleFilename, rule, backupReasonGameStopped)
+		if backedUp > 0 {
+			a.AddEvent(fmt.Sprintf("Backed up %d files of %s after it stopped", backedUp, rule.Name))
+		}
+	}
+
+	a.endSession(rule)
 }
 
 func (a *App) findBackupMetadata(ruleFilename string) []BackupMetadata {
@@ -665,16 +1249,65 @@ func (a *App) findBackupMetadataForRestore(ruleFilename string, filename string)
 	return BackupMetadata{}
 }
 
-// IsRunning checks if this process is currently running
-func (a *App) IsRunning(exePattern glob.Glob) bool {
-	for _, exeFullPath := range a.ProcessList {
-		match := exePattern.Match(exeFullPath)
+// IsRunning checks if any of the running processes matches the rule
+func (a *App) IsRunning(rule ActiveRule) bool {
+	_, _, running := a.findGameProcess(rule)
+	return running
+}
 
-		if match {
-			return true
+// findGameProcess finds the running process of the game, and the matcher that detected it
+func (a *App) findGameProcess(rule ActiveRule) (processMatcher, ProcessInfo, bool) {
+	for _, proc := range a.ProcessList {
+		if matcher, ok := rule.matchProcess(proc); ok {
+			return matcher, proc, true
 		}
 	}
-	return false
+	return processMatcher{}, ProcessInfo{}, false
+}
+
+// matchProcess checks if the process is the game, and not e.g. its launcher
+func (rule ActiveRule) matchProcess(proc ProcessInfo) (processMatcher, bool) {
+	for _, exclude := range rule.excludeProcessGlobs {
+		if exclude.Match(proc.Exe) || exclude.Match(proc.Cmdline) || exclude.Match(proc.Name) {
+			return processMatcher{}, false
+		}
+	}
+
+	for _, matcher := range rule.processMatchers {
+		if matcher.match(proc) {
+			return matcher, true
+		}
+	}
+
+	return processMatcher{}, false
+}
+
+func (m processMatcher) match(proc ProcessInfo) bool {
+	value := m.value(proc)
+	return value != "" && m.glob.Match(value)
+}
+
+// value returns the part of the process the matcher looks at
+func (m processMatcher) value(proc ProcessInfo) string {
+	switch m.kind {
+	case matchExecutable:
+		return proc.Exe
+	case matchCmdline:
+		return proc.Cmdline
+	case matchProcessName:
+		return proc.Name
+	case matchWineExecutable:
+		return strings.ToLower(proc.Cmdline)
+	}
+	return ""
+}
+
+// emit sends an event to the UI, if we have one
+func (a *App) emit(eventName string, data ...interface{}) {
+	if a.headless {
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, eventName, data...)
 }
 
 // AddEvent adds an event to the event log
@@ -687,7 +1320,12 @@ func (a *App) AddEvent(msg string) {
 	}
 
 	a.Events = events[:last]
-	wailsRuntime.EventsEmit(a.ctx, "eventsUpdated", a.Events)
+	if a.headless {
+		fmt.Println(msg)
+		return
+	}
+
+	a.emit("eventsUpdated", a.Events)
 	wailsRuntime.LogPrint(a.ctx, msg)
 }
 
@@ -701,7 +1339,12 @@ func (a *App) ReportError(err error) {
 	}
 
 	a.Errors = errors[:last]
-	wailsRuntime.EventsEmit(a.ctx, "errorsUpdated", a.Errors)
+	if a.headless {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+
+	a.emit("errorsUpdated", a.Errors)
 	wailsRuntime.LogError(a.ctx, err.Error())
 }
 
@@ -751,9 +1394,32 @@ func (a *App) menu() *menu.Menu {
 
 	fileMenu := appMenu.AddSubmenu("File")
 
+	// Menu callbacks can run on the UI thread, so they must not wait for the monitor goroutine
 	fileMenu.AddText("Reload rules", keys.CmdOrCtrl("r"), func(_ *menu.CallbackData) {
-		a.LoadRules()
-		a.CheckRules()
+		go a.onMonitor(func() {
+			a.LoadRules()
+			a.CheckRules()
+		})
+	})
+
+	// The banner in the UI shows if monitoring is paused
+	pauseMenu := fileMenu.AddSubmenu("Pause monitoring")
+	pauseMenu.AddText("For 15 minutes", nil, func(_ *menu.CallbackData) {
+		go a.Pause(15)
+	})
+	pauseMenu.AddText("For 1 hour", nil, func(_ *menu.CallbackData) {
+		go a.Pause(60)
+	})
+	pauseMenu.AddText("Until resumed", nil, func(_ *menu.CallbackData) {
+		go a.Pause(0)
+	})
+
+	fileMenu.AddText("Resume monitoring", nil, func(_ *menu.CallbackData) {
+		go a.Resume()
+	})
+
+	fileMenu.AddText("Back up all games", keys.CmdOrCtrl("b"), func(_ *menu.CallbackData) {
+		go a.BackupAll()
 	})
 
 	fileMenu.AddText("Quit", keys.CmdOrCtrl("q"), func(_ *menu.CallbackData) {
@@ -774,16 +1440,23 @@ func (a *App) startup(ctx context.Context) {
 	// Ensure our paths exist
 	a.ensurePath(a.getBackupsPath())
 	a.ensurePath(a.getRulesPath())
+	a.ensurePath(a.getSessionsPath())
+
+	// Watch for changes to savegames instead of polling where possible
+	a.startWatcher()
 
 	// Try to load config and rules
 	a.LoadConfig()
 	a.LoadRules()
 	a.CheckRules()
 
-	// Start the monitor
+	// Start the monitor, and let `baacup run` know we're here
 	go a.runMonitor()
+	a.startInstanceServer()
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	a.stopInstanceServer()
+	a.endAllSessions()
 	a.exit <- true
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestApp creates an app without the GUI that keeps its backups in a temporary folder
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	a := newApp()
+	a.headless = true
+	a.BasePath = t.TempDir()
+	return a
+}
+
+// writeTestFile creates the file and the folders it's in
+func writeTestFile(t *testing.T, path string, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeBackupSameNameAndTime(t *testing.T) {
+	a := newTestApp(t)
+	savesPath := t.TempDir()
+
+	backupTime := time.Now()
+	filenames := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		source := filepath.Join(savesPath, fmt.Sprintf("world%d", i), "level.dat")
+		writeTestFile(t, source, fmt.Sprintf("world %d", i))
+
+		meta, err := a.makeBackup("game", BackupMetadata{Source: source, BackupTime: backupTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filenames[meta.Filename] {
+			t.Fatalf("backup of %s reused the name %s", source, meta.Filename)
+		}
+		filenames[meta.Filename] = true
+
+		contents, err := os.ReadFile(filepath.Join(a.getBackupsPath(), "game", meta.Filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != fmt.Sprintf("world %d", i) {
+			t.Fatalf("backup %s has the contents %q of another file", meta.Filename, contents)
+		}
+	}
+
+	backups := a.findBackupMetadata("game")
+	if len(backups) != 3 {
+		t.Fatalf("found %d backups, want 3", len(backups))
+	}
+	for _, meta := range backups {
+		if !filenames[meta.Filename] {
+			t.Errorf("metadata points to unknown backup %s", meta.Filename)
+		}
+	}
+}
+
+func TestBackupAllFilesSameName(t *testing.T) {
+	a := newTestApp(t)
+	savesPath := t.TempDir()
+
+	const worlds = 20
+	for i := 0; i < worlds; i++ {
+		writeTestFile(t, filepath.Join(savesPath, "saves", fmt.Sprintf("world%02d", i), "level.dat"), fmt.Sprintf("world %d", i))
+	}
+
+	rule := ActiveRule{
+		RuleFilename: "game",
+		Name:         "Game",
+		Platform: RulePlatform{
+			Savegames: []string{filepath.Join(savesPath, "saves", "*", "level.dat")},
+		},
+	}
+	a.Rules["game"] = rule
+
+	backedUp := a.backupAllFiles("game", rule, "")
+	if backedUp != worlds {
+		t.Fatalf("backed up %d files, want %d", backedUp, worlds)
+	}
+
+	files, err := filepath.Glob(filepath.Join(a.getBackupsPath(), "game", "level-*.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != worlds {
+		t.Fatalf("found %d backup files, want %d", len(files), worlds)
+	}
+	if len(a.Backups["game"]) != worlds {
+		t.Fatalf("found %d backups in memory, want %d", len(a.Backups["game"]), worlds)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Named captures in executable and cmdline patterns look like <name>, and match like *
+var (
+	captureRegexp      = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_]*)>`)
+	captureStartRegexp = regexp.MustCompile(`^<([A-Za-z_][A-Za-z0-9_]*)>`)
+)
+
+// Captures are used in savegame patterns as ${match.name}
+var matchVariableRegexp = regexp.MustCompile(`\$\{match\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// hasCaptures tells if the process pattern captures any named parts
+func hasCaptures(pattern string) bool {
+	return captureRegexp.MatchString(pattern)
+}
+
+// captureGlob turns the captures in the pattern into plain wildcards for matching
+func captureGlob(pattern string) string {
+	return captureRegexp.ReplaceAllString(pattern, "*")
+}
+
+// captureRegexpFor translates the glob pattern into a regexp with the captures as named groups
+func captureRegexpFor(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	expr := strings.Builder{}
+	if ignoreCase {
+		expr.WriteString("(?i)")
+	}
+	expr.WriteString("^")
+
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '<' && captureStartRegexp.MatchString(pattern[i:]):
+			match := captureStartRegexp.FindStringSubmatch(pattern[i:])
+			expr.WriteString(fmt.Sprintf("(?P<%s>.*)", match[1]))
+			i += len(match[0]) - 1
+
+		case c == '*':
+			// ** is the same as * as we don't use separators
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			expr.WriteString(".*")
+
+		case c == '?':
+			expr.WriteString(".")
+
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %s", pattern)
+			}
+
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + strings.TrimPrefix(class, "!")
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+
+		case c == '{':
+			depth++
+			expr.WriteString("(?:")
+
+		case c == '}' && depth > 0:
+			depth--
+			expr.WriteString(")")
+
+		case c == ',' && depth > 0:
+			expr.WriteString("|")
+
+		default:
+			// Bytes of multi-byte characters are passed through as they are
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// capture returns the named parts of the process the matcher captured, if any
+func (m processMatcher) capture(proc ProcessInfo) map[string]string {
+	captures := map[string]string{}
+	if m.captures == nil {
+		return captures
+	}
+
+	// Wine command lines are matched in lower case, but we want to keep the case of the captures
+	value := m.value(proc)
+	if m.kind == matchWineExecutable {
+		value = proc.Cmdline
+	}
+
+	match := m.captures.FindStringSubmatch(value)
+	if match == nil {
+		return captures
+	}
+
+	for i, name := range m.captures.SubexpNames() {
+		if name != "" {
+			captures[name] = match[i]
+		}
+	}
+
+	return captures
+}
+
+// escapeGlob quotes a captured value for use in a savegame pattern
+func escapeGlob(value string) string {
+	replacer := strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
+	if runtime.GOOS != "windows" {
+		// Backslashes are separators on Windows, and escapes elsewhere
+		replacer = strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]", `\`, `\\`)
+	}
+	return replacer.Replace(value)
+}
+
+// applyCaptures fills in the ${match.name} variables in the savegame patterns. Parts that weren't
+// captured, e.g. when the game is not running, match anything.
+func applyCaptures(patterns []string, captures map[string]string) []string {
+	result := []string{}
+	for _, pattern := range patterns {
+		result = append(result, matchVariableRegexp.ReplaceAllStringFunc(pattern, func(variable string) string {
+			name := matchVariableRegexp.FindStringSubmatch(variable)[1]
+			if value, ok := captures[name]; ok {
+				return escapeGlob(value)
+			}
+			return "*"
+		}))
+	}
+
+	return result
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCaptureRegexpFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		ignoreCase bool
+		value      string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:    "capture",
+			pattern: "*retroarch -L *.so <rom>",
+			value:   "/usr/bin/retroarch -L snes.so /roms/Chrono Trigger.sfc",
+			want:    map[string]string{"rom": "/roms/Chrono Trigger.sfc"},
+		},
+		{
+			name:    "several captures",
+			pattern: "/games/<game>/bin/<arch>/game",
+			value:   "/games/Tyrian/bin/x86_64/game",
+			want:    map[string]string{"game": "Tyrian", "arch": "x86_64"},
+		},
+		{
+			name:    "question mark and double star",
+			pattern: "**/dosbox? -conf <conf>.conf",
+			value:   "/usr/bin/dosbox- -conf /games/keen.conf",
+			want:    map[string]string{"conf": "/games/keen"},
+		},
+		{
+			name:    "alternatives",
+			pattern: "*/{scummvm,residualvm} <game>",
+			value:   "/usr/bin/residualvm grim",
+			want:    map[string]string{"game": "grim"},
+		},
+		{name: "alternatives don't match", pattern: "*/{scummvm,residualvm} <game>", value: "/usr/bin/dosbox grim"},
+		{
+			name:    "negated class",
+			pattern: "/games/[!.]<game>",
+			value:   "/games/Tyrian",
+			want:    map[string]string{"game": "yrian"},
+		},
+		{name: "negated class doesn't match", pattern: "/games/[!.]<game>", value: "/games/.hidden"},
+		{
+			name:    "escaped wildcard",
+			pattern: `/games/\*<game>`,
+			value:   "/games/*Tyrian",
+			want:    map[string]string{"game": "Tyrian"},
+		},
+		{name: "escaped wildcard is literal", pattern: `/games/\*<game>`, value: "/games/Tyrian"},
+		{
+			name:    "regexp characters are literal",
+			pattern: "/games/(1+1).exe <game>",
+			value:   "/games/(1+1).exe pong",
+			want:    map[string]string{"game": "pong"},
+		},
+		{
+			name:       "ignore case",
+			pattern:    `c:\\games\\<game>.exe`,
+			ignoreCase: true,
+			value:      `C:\Games\Tyrian.EXE`,
+			want:       map[string]string{"game": "Tyrian"},
+		},
+		{name: "case matters", pattern: "/Games/<game>", value: "/games/Tyrian"},
+		{name: "not a capture", pattern: "/games/<1game>", value: "/games/<1game>", want: map[string]string{}},
+		{name: "unterminated class", pattern: "/games/[abc<game>", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := captureRegexpFor(test.pattern, test.ignoreCase)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			matcher := processMatcher{kind: matchCmdline, captures: expr}
+			got := matcher.capture(ProcessInfo{Cmdline: test.value})
+			if test.want == nil {
+				if expr.MatchString(test.value) {
+					t.Fatalf("%s matched %q", expr, test.value)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyCaptures(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		captures map[string]string
+		path     string
+		want     string
+	}{
+		{
+			name:     "captured",
+			pattern:  "/saves/${match.rom}.srm",
+			captures: map[string]string{"rom": "Chrono Trigger"},
+			path:     "/saves/Chrono Trigger.srm",
+			want:     "/saves/Chrono Trigger.srm",
+		},
+		{
+			name:    "not captured",
+			pattern: "/saves/${match.rom}.srm",
+			path:    "/saves/Anything.srm",
+			want:    "/saves/*.srm",
+		},
+		{
+			name:     "wildcards in the capture are literal",
+			pattern:  "/saves/${match.rom}.srm",
+			captures: map[string]string{"rom": "Game*[v1]?"},
+			path:     "/saves/Game*[v1]?.srm",
+			want:     "/saves/Game[*][[]v1][?].srm",
+		},
+		{
+			name:     "other variables are left alone",
+			pattern:  "${GAME_DIR}/${match.rom}",
+			captures: map[string]string{"rom": "doom"},
+			path:     "${GAME_DIR}/doom",
+			want:     "${GAME_DIR}/doom",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := applyCaptures([]string{test.pattern}, test.captures)[0]
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+
+			match, err := filepath.Match(got, test.path)
+			if err != nil || !match {
+				t.Fatalf("%q doesn't match %q: %v", got, test.path, err)
+			}
+		})
+	}
+}
+
+func TestEscapeGlobBackslashes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("backslashes are separators on Windows")
+	}
+
+	pattern := applyCaptures([]string{"/saves/${match.rom}"}, map[string]string{"rom": `a\b*`})[0]
+	match, err := filepath.Match(pattern, `/saves/a\b*`)
+	if err != nil || !match {
+		t.Fatalf("%q doesn't match the captured value: %v", pattern, err)
+	}
+}
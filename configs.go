@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Backups are either savegames or the game's configuration, e.g. settings and keybindings
+const (
+	backupCategorySavegame = "savegame"
+	backupCategoryConfig   = "config"
+)
+
+// filePatterns returns the patterns of all the files of the rule to back up
+func (rule ActiveRule) filePatterns() []string {
+	return append(append([]string{}, rule.Platform.Savegames...), rule.Platform.Configs...)
+}
+
+// fileCategory tells if the file is a savegame or a config file of the rule
+func (rule ActiveRule) fileCategory(path string) string {
+	for _, pattern := range rule.Platform.Savegames {
+		if match, err := filepath.Match(pattern, path); err == nil && match {
+			return backupCategorySavegame
+		}
+	}
+
+	for _, pattern := range rule.Platform.Configs {
+		if match, err := filepath.Match(pattern, path); err == nil && match {
+			return backupCategoryConfig
+		}
+	}
+
+	return backupCategorySavegame
+}
+
+// category returns the category of the backup, backups from before configs were backed up are all
+// savegames
+func (meta BackupMetadata) category() string {
+	if meta.Category == "" {
+		return backupCategorySavegame
+	}
+	return meta.Category
+}
+
+// inCategory tells if the backup should be restored for the category, an empty category means all
+func (meta BackupMetadata) inCategory(category string) bool {
+	return category == "" || meta.category() == category
+}
+
+// latestBackups finds the latest backup made before the time of each file in the category, or of
+// all files if the category is empty
+func (a *App) latestBackups(ruleFilename string, before time.Time, category string) []BackupMetadata {
+	latest := map[string]BackupMetadata{}
+	for _, meta := range a.Backups[ruleFilename] {
+		if meta.BackupTime.After(before) || !meta.inCategory(category) {
+			continue
+		}
+
+		if previous, ok := latest[meta.Source]; !ok || meta.BackupTime.After(previous.BackupTime) {
+			latest[meta.Source] = meta
+		}
+	}
+
+	backups := []BackupMetadata{}
+	for _, meta := range latest {
+		backups = append(backups, meta)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Source < backups[j].Source
+	})
+
+	return backups
+}
+
+// restoreBackups restores all the backups, even if some of them fail
+func (a *App) restoreBackups(ruleFilename string, backups []BackupMetadata) bool {
+	success := true
+	for _, meta := range backups {
+		if !a.restoreBackup(ruleFilename, meta.Filename) {
+			success = false
+		}
+	}
+
+	return success
+}
+
+// RestoreLatest restores the latest backups of the savegames or configs of a game, or both if the
+// category is empty
+func (a *App) RestoreLatest(ruleFilename string, category string) bool {
+	success := false
+	a.onMonitor(func() {
+		success = a.restoreLatest(ruleFilename, category)
+	})
+	return success
+}
+
+func (a *App) restoreLatest(ruleFilename string, category string) bool {
+	if _, ok := a.Backups[ruleFilename]; !ok {
+		a.Backups[ruleFilename] = a.findBackupMetadata(ruleFilename)
+	}
+
+	backups := a.latestBackups(ruleFilename, time.Now(), category)
+	if len(backups) == 0 {
+		a.ReportError(fmt.Errorf("no backups of %s found to restore", ruleFilename))
+		return false
+	}
+
+	return a.restoreBackups(ruleFilename, backups)
+}
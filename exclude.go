@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ruleConfig returns the user's settings for the rule from config.yaml, which can be under the rule
+// name with or without the .yaml extension
+func (a *App) ruleConfig(ruleFilename string) *RuleConfig {
+	for key, ruleConfig := range a.Config.Rules {
+		if ruleConfig != nil && strings.TrimSuffix(key, ".yaml") == ruleFilename {
+			return ruleConfig
+		}
+	}
+
+	return &RuleConfig{}
+}
+
+// isNamePattern tells if the exclude pattern is for file names, e.g. *.log, instead of full paths
+func isNamePattern(pattern string) bool {
+	return !strings.ContainsAny(pattern, `/\$`)
+}
+
+// excludePatterns expands the exclude patterns of the rule and the user's config for it
+func (a *App) excludePatterns(rule ActiveRule, winePrefixes []WinePrefix) []string {
+	patterns := append([]string{}, rule.Platform.Exclude...)
+	patterns = append(patterns, a.ruleConfig(rule.RuleFilename).Exclude...)
+
+	excludes := []string{}
+	for _, pattern := range patterns {
+		switch {
+		case isNamePattern(pattern) && rule.ViaWine:
+			excludes = append(excludes, caseInsensitivePattern(pattern))
+
+		case isNamePattern(pattern):
+			excludes = append(excludes, pattern)
+
+		default:
+			excludes = append(excludes, a.expandFilePatterns(rule, []string{pattern}, winePrefixes)...)
+		}
+	}
+
+	return excludes
+}
+
+// hasGlobMeta tells if the pattern has wildcards or escapes in it
+func hasGlobMeta(pattern string) bool {
+	meta := `*?[`
+	if runtime.GOOS != "windows" {
+		meta = `*?[\`
+	}
+	return strings.ContainsAny(pattern, meta)
+}
+
+// globRoot returns the folder the files matching the pattern are in, up to the first wildcard, e.g.
+// /saves for /saves/*/level.dat
+func globRoot(pattern string) string {
+	root := filepath.Dir(pattern)
+	for hasGlobMeta(root) {
+		parent := filepath.Dir(root)
+		if parent == root {
+			break
+		}
+		root = parent
+	}
+
+	return root
+}
+
+// isExcluded tells if the file matching the savegame pattern, or any folder it's in below the fixed
+// part of the pattern, matches any of the exclude patterns. Patterns without a folder match the
+// names, others the full paths. Folders above the fixed part, e.g. ~/.local, are not checked so that
+// excluding .* or logs doesn't exclude everything in them.
+func isExcluded(path string, pattern string, excludes []string) bool {
+	root := globRoot(pattern)
+	for {
+		for _, exclude := range excludes {
+			target := path
+			if isNamePattern(exclude) {
+				target = filepath.Base(path)
+			}
+
+			if match, err := filepath.Match(exclude, target); err == nil && match {
+				return true
+			}
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path || len(parent) <= len(root) {
+			return false
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsExcluded(t *testing.T) {
+	game := filepath.FromSlash("/home/user/.local/share/Game")
+
+	tests := []struct {
+		name     string
+		path     string
+		pattern  string
+		excludes []string
+		want     bool
+	}{
+		{
+			name:     "file name",
+			path:     filepath.Join(game, "debug.log"),
+			pattern:  filepath.Join(game, "*"),
+			excludes: []string{"*.log"},
+			want:     true,
+		},
+		{
+			name:     "other file name",
+			path:     filepath.Join(game, "slot1.sav"),
+			pattern:  filepath.Join(game, "*"),
+			excludes: []string{"*.log"},
+		},
+		{
+			name:     "full path",
+			path:     filepath.Join(game, "shadercache"),
+			pattern:  filepath.Join(game, "*"),
+			excludes: []string{filepath.Join(game, "shadercache")},
+			want:     true,
+		},
+		{
+			name:     "folder below the wildcard",
+			path:     filepath.Join(game, "saves", ".backup", "level.dat"),
+			pattern:  filepath.Join(game, "saves", "*", "level.dat"),
+			excludes: []string{".*"},
+			want:     true,
+		},
+		{
+			name:     "full path of folder below the wildcard",
+			path:     filepath.Join(game, "saves", "cache", "level.dat"),
+			pattern:  filepath.Join(game, "saves", "*", "level.dat"),
+			excludes: []string{filepath.Join(game, "saves", "cache")},
+			want:     true,
+		},
+		{
+			name:     "hidden folder above the pattern",
+			path:     filepath.Join(game, "slot1.sav"),
+			pattern:  filepath.Join(game, "*.sav"),
+			excludes: []string{".*"},
+		},
+		{
+			name:     "folder of the pattern",
+			path:     filepath.FromSlash("/home/user/.cache/Game/logs/slot1.sav"),
+			pattern:  filepath.FromSlash("/home/user/.cache/Game/logs/*.sav"),
+			excludes: []string{"logs", ".*"},
+		},
+		{
+			name:     "folder above the wildcard",
+			path:     filepath.Join(game, "saves", "world1", "level.dat"),
+			pattern:  filepath.Join(game, "saves", "*", "level.dat"),
+			excludes: []string{"saves"},
+		},
+		{
+			name:     "pattern without wildcards",
+			path:     filepath.Join(game, "settings.ini"),
+			pattern:  filepath.Join(game, "settings.ini"),
+			excludes: []string{"Game", ".*"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isExcluded(test.path, test.pattern, test.excludes); got != test.want {
+				t.Fatalf("isExcluded(%q, %q, %q) = %v, want %v", test.path, test.pattern, test.excludes, got, test.want)
+			}
+		})
+	}
+}
@@ -7,15 +7,32 @@ import {
   GetConfig,
   GetErrors,
   GetEvents,
+  GetPauseState,
   GetRules,
 } from "../wailsjs/go/main/App"
 import { EventsOff, EventsOn } from "../wailsjs/runtime"
 
 import type { main } from "../wailsjs/go/models"
 
+type SaveLabel = {
+  pattern: string
+  label: string
+}
+
 type RulePlatform = {
   executable: string
+  executables: string[]
+  cmdline: string
+  processName: string
+  excludeProcesses: string[]
+  steamAppId: string
+  flatpaks: string[]
+  snaps: string[]
   savegames: string[]
+  configs: string[]
+  exclude: string[]
+  labels: SaveLabel[]
+  thumbnail: string
 }
 
 export type ActiveRule = {
@@ -23,13 +40,35 @@ export type ActiveRule = {
   issues: string
   name: string
   platform: RulePlatform
+  viaWine: boolean
+  disabled: boolean
+  detectedBy: string
+  detectedProcess: string
+  installed: InstalledGame | null
+  captures: { [key: string]: string } | null
+  gameDir: string
+}
+
+type InstalledGame = {
+  launcher: string
+  id: string
+  name: string
+  installDir: string
+  executable: string
+  prefix: string
 }
 
 export type BackupMetadata = {
   filename: string
   source: string
+  category: string
+  label: string
   backupTime: string
   lastModified: string
+  reason: string
+  thumbnail: string
+  inspector: string
+  details: { [key: string]: string }
 }
 
 export const eventStore: Readable<string[]> = readable([], function start(set) {
@@ -62,6 +101,27 @@ export const errorStore: Readable<string[]> = readable([], function start(set) {
   }
 })
 
+export type PauseState = {
+  paused: boolean
+  until: string | null
+  rules: { [key: string]: string | null }
+}
+
+export const pauseStore: Readable<PauseState> = readable(undefined, function start(set) {
+  async function getData() {
+    set(await GetPauseState())
+  }
+
+  getData().then(() => {})
+  EventsOn("pausedUpdated", function (data) {
+    set(data)
+  })
+
+  return () => {
+    EventsOff("pausedUpdated")
+  }
+})
+
 export const configStore: Readable<main.Config> = readable(undefined, function start(set) {
   async function getData() {
     set(await GetConfig())
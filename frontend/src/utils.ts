@@ -14,3 +14,9 @@ export function formatDateTime(dt: string): string {
 
   return dateTimeformatter.format(d) + " " + new Date(d).toLocaleTimeString()
 }
+
+export function formatDuration(seconds: number): string {
+  const hours = Math.floor(seconds / 3600)
+  const minutes = Math.floor(seconds / 60) % 60
+  return `${hours}h ${minutes.toString().padStart(2, "0")}m`
+}
@@ -1,22 +1,33 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
-import {glob} from '../models';
 
 export function AddEvent(arg1:string):Promise<void>;
 
+export function BackupAll():Promise<number>;
+
+export function BackupNow(arg1:string):Promise<number>;
+
 export function CheckRules():Promise<void>;
 
 export function DeleteBackup(arg1:string,arg2:string):Promise<void>;
 
+export function DisableRule(arg1:string):Promise<boolean>;
+
 export function DownloadRules():Promise<void>;
 
+export function EnableRule(arg1:string):Promise<boolean>;
+
 export function GetActiveBackups():Promise<{[key: string]: Array<main.BackupMetadata>}>;
 
 export function GetActiveMonitors():Promise<Array<main.Monitor>>;
 
 export function GetActiveRules():Promise<{[key: string]: main.ActiveRule}>;
 
+export function GetAllSessions():Promise<{[key: string]: Array<main.PlaySession>}>;
+
+export function GetBackupThumbnail(arg1:string,arg2:string):Promise<string>;
+
 export function GetConfig():Promise<main.Config>;
 
 export function GetErrors():Promise<Array<string>>;
@@ -25,16 +36,36 @@ export function GetEvents():Promise<Array<string>>;
 
 export function GetPathSeparator():Promise<string>;
 
+export function GetPauseState():Promise<main.PauseState>;
+
+export function GetPlaytime(arg1:string):Promise<number>;
+
 export function GetRules():Promise<{[key: string]: main.ActiveRule}>;
 
-export function IsRunning(arg1:glob.Glob):Promise<boolean>;
+export function GetSessions(arg1:string):Promise<Array<main.PlaySession>>;
+
+export function IsRunning(arg1:main.ActiveRule):Promise<boolean>;
 
 export function LoadConfig():Promise<void>;
 
 export function LoadRules():Promise<void>;
 
+export function Pause(arg1:number):Promise<void>;
+
+export function PauseRule(arg1:string,arg2:number):Promise<boolean>;
+
 export function ReportError(arg1:Error):Promise<void>;
 
 export function RestoreBackup(arg1:string,arg2:string):Promise<boolean>;
 
+export function RestoreLatest(arg1:string,arg2:string):Promise<boolean>;
+
+export function RestoreSession(arg1:string,arg2:string,arg3:string):Promise<boolean>;
+
+export function Resume():Promise<void>;
+
+export function ResumeRule(arg1:string):Promise<boolean>;
+
 export function SaveConfig():Promise<void>;
+
+export function Sweep():Promise<number>;
@@ -6,6 +6,14 @@ export function AddEvent(arg1) {
   return window['go']['main']['App']['AddEvent'](arg1);
 }
 
+export function BackupAll() {
+  return window['go']['main']['App']['BackupAll']();
+}
+
+export function BackupNow(arg1) {
+  return window['go']['main']['App']['BackupNow'](arg1);
+}
+
 export function CheckRules() {
   return window['go']['main']['App']['CheckRules']();
 }
@@ -14,10 +22,18 @@ export function DeleteBackup(arg1, arg2) {
   return window['go']['main']['App']['DeleteBackup'](arg1, arg2);
 }
 
+export function DisableRule(arg1) {
+  return window['go']['main']['App']['DisableRule'](arg1);
+}
+
 export function DownloadRules() {
   return window['go']['main']['App']['DownloadRules']();
 }
 
+export function EnableRule(arg1) {
+  return window['go']['main']['App']['EnableRule'](arg1);
+}
+
 export function GetActiveBackups() {
   return window['go']['main']['App']['GetActiveBackups']();
 }
@@ -30,6 +46,14 @@ export function GetActiveRules() {
   return window['go']['main']['App']['GetActiveRules']();
 }
 
+export function GetAllSessions() {
+  return window['go']['main']['App']['GetAllSessions']();
+}
+
+export function GetBackupThumbnail(arg1, arg2) {
+  return window['go']['main']['App']['GetBackupThumbnail'](arg1, arg2);
+}
+
 export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
@@ -46,10 +70,22 @@ export function GetPathSeparator() {
   return window['go']['main']['App']['GetPathSeparator']();
 }
 
+export function GetPauseState() {
+  return window['go']['main']['App']['GetPauseState']();
+}
+
+export function GetPlaytime(arg1) {
+  return window['go']['main']['App']['GetPlaytime'](arg1);
+}
+
 export function GetRules() {
   return window['go']['main']['App']['GetRules']();
 }
 
+export function GetSessions(arg1) {
+  return window['go']['main']['App']['GetSessions'](arg1);
+}
+
 export function IsRunning(arg1) {
   return window['go']['main']['App']['IsRunning'](arg1);
 }
@@ -62,6 +98,14 @@ export function LoadRules() {
   return window['go']['main']['App']['LoadRules']();
 }
 
+export function Pause(arg1) {
+  return window['go']['main']['App']['Pause'](arg1);
+}
+
+export function PauseRule(arg1, arg2) {
+  return window['go']['main']['App']['PauseRule'](arg1, arg2);
+}
+
 export function ReportError(arg1) {
   return window['go']['main']['App']['ReportError'](arg1);
 }
@@ -70,6 +114,26 @@ export function RestoreBackup(arg1, arg2) {
   return window['go']['main']['App']['RestoreBackup'](arg1, arg2);
 }
 
+export function RestoreLatest(arg1, arg2) {
+  return window['go']['main']['App']['RestoreLatest'](arg1, arg2);
+}
+
+export function RestoreSession(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RestoreSession'](arg1, arg2, arg3);
+}
+
+export function Resume() {
+  return window['go']['main']['App']['Resume']();
+}
+
+export function ResumeRule(arg1) {
+  return window['go']['main']['App']['ResumeRule'](arg1);
+}
+
 export function SaveConfig() {
   return window['go']['main']['App']['SaveConfig']();
 }
+
+export function Sweep() {
+  return window['go']['main']['App']['Sweep']();
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const gameDirVariable = "${GAME_DIR}"
+
+func (a *App) getGameDirsPath() string {
+	return filepath.Join(a.BasePath, "game_dirs.yaml")
+}
+
+// loadGameDirs reads the last known install folders of the games
+func (a *App) loadGameDirs() map[string]string {
+	gameDirs := map[string]string{}
+	gameDirsPath := a.getGameDirsPath()
+
+	contents, err := os.ReadFile(gameDirsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.ReportError(err)
+		}
+		return gameDirs
+	}
+
+	err = yaml.Unmarshal(contents, &gameDirs)
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error parsing game folders from %s", gameDirsPath))
+		return map[string]string{}
+	}
+
+	return gameDirs
+}
+
+func (a *App) saveGameDirs() {
+	gameDirsPath := a.getGameDirsPath()
+
+	data, err := yaml.Marshal(a.gameDirs)
+	if err == nil {
+		err = os.WriteFile(gameDirsPath, data, 0o600)
+	}
+
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error writing game folders to %s", gameDirsPath))
+	}
+}
+
+// knownGameDir returns where the game was last seen running, or where a launcher says it's installed
+func (a *App) knownGameDir(rule ActiveRule) string {
+	if gameDir, ok := a.gameDirs[rule.RuleFilename]; ok {
+		return gameDir
+	}
+
+	if rule.Installed != nil && rule.Installed.InstallDir != "" {
+		return rule.Installed.InstallDir
+	}
+
+	if app, ok := a.getSteam().Apps[rule.Platform.SteamAppID]; ok {
+		return filepath.Join(app.Library, "steamapps", "common", app.InstallDir)
+	}
+
+	return ""
+}
+
+// rememberGameDir stores the install folder of the running game, so sweeps can find its saves while
+// it's not running
+func (a *App) rememberGameDir(rule ActiveRule) {
+	if rule.GameDir == "" || a.gameDirs[rule.RuleFilename] == rule.GameDir {
+		return
+	}
+
+	a.gameDirs[rule.RuleFilename] = rule.GameDir
+	a.saveGameDirs()
+
+	if known, ok := a.Rules[rule.RuleFilename]; ok {
+		known.resolveSavegames(rule.GameDir)
+		a.Rules[rule.RuleFilename] = known
+	}
+}
+
+// detectedGameDir finds the install folder of the game from the process that was detected. Process
+// names and command lines don't say where the game is, and for games running via Wine the executable
+// of the process is Wine or Proton itself.
+func detectedGameDir(rule ActiveRule, matcher processMatcher, proc ProcessInfo) string {
+	switch matcher.kind {
+	case matchExecutable:
+		if proc.Exe != "" && !rule.ViaWine {
+			return filepath.Dir(proc.Exe)
+		}
+
+	case matchSteamApp:
+		// The install folder is all we know of the process
+		return proc.Exe
+
+	case matchWineExecutable:
+		// Proton runs the game with the Linux path of the executable, e.g.
+		// wine64 /home/user/.steam/steam/steamapps/common/Game/game.exe
+		end := strings.LastIndex(strings.ToLower(proc.Cmdline), ".exe")
+		if end < 0 {
+			return ""
+		}
+
+		path := proc.Cmdline[:end+len(".exe")]
+		if start := strings.LastIndex(path, " /"); start >= 0 {
+			path = path[start+1:]
+		}
+		if strings.HasPrefix(path, "/") {
+			return filepath.Dir(path)
+		}
+	}
+
+	return ""
+}
+
+// applyGameDir fills in ${GAME_DIR} in the savegame patterns, dropping the patterns that need it if
+// we don't know where the game is installed
+func applyGameDir(patterns []string, gameDir string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, gameDirVariable) {
+			if gameDir == "" {
+				continue
+			}
+			pattern = strings.ReplaceAll(pattern, gameDirVariable, escapeGlob(gameDir))
+		}
+
+		// Wine patterns using ${GAME_DIR} are the same in every prefix
+		if !seen[pattern] {
+			seen[pattern] = true
+			result = append(result, pattern)
+		}
+	}
+
+	return result
+}
+
+// resolveSavegames fills in the savegame and config patterns with what we know of the game
+func (rule *ActiveRule) resolveSavegames(gameDir string) {
+	rule.GameDir = gameDir
+	rule.Platform.Savegames = applyGameDir(applyCaptures(rule.savegameTemplates, rule.Captures), gameDir)
+	rule.Platform.Configs = applyGameDir(applyCaptures(rule.configTemplates, rule.Captures), gameDir)
+	rule.Platform.Exclude = applyGameDir(applyCaptures(rule.excludeTemplates, rule.Captures), gameDir)
+}
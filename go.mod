@@ -3,6 +3,7 @@ module baacup
 go 1.18
 
 require (
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/gobwas/glob v0.2.3
 	github.com/goccy/go-yaml v1.11.0
 	github.com/shirou/gopsutil/v3 v3.23.7
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Well known keys for the details extracted from savegames
+const (
+	detailCharacter = "character"
+	detailLevel     = "level"
+	detailPlaytime  = "playtime"
+	detailLocation  = "location"
+	detailSaveName  = "saveName"
+)
+
+// SaveInspector knows how to extract in-game metadata from a savegame format
+type SaveInspector interface {
+	// Name is stored in the backup metadata to tell which inspector produced the details
+	Name() string
+	// Matches checks if the file looks like something this inspector could read
+	Matches(filePath string) bool
+	// Inspect parses the file and returns the details it could find
+	Inspect(filePath string) (map[string]string, error)
+}
+
+// saveInspectors lists the inspectors in the order they are tried
+var saveInspectors = []SaveInspector{
+	renpyInspector{},
+	rpgMakerInspector{},
+	godotResourceInspector{},
+	godotJSONInspector{},
+}
+
+// RegisterSaveInspector adds a new inspector, taking priority over the built-in ones
+func RegisterSaveInspector(inspector SaveInspector) {
+	saveInspectors = append([]SaveInspector{inspector}, saveInspectors...)
+}
+
+// inspectSave runs the first matching inspector that finds anything from the file
+func (a *App) inspectSave(filePath string) (string, map[string]string) {
+	for _, inspector := range saveInspectors {
+		if !inspector.Matches(filePath) {
+			continue
+		}
+
+		details, err := inspector.Inspect(filePath)
+		if err != nil {
+			// Plenty of files share extensions with formats we know, no need to spam errors
+			continue
+		}
+
+		if len(details) > 0 {
+			return inspector.Name(), details
+		}
+	}
+
+	return "", nil
+}
+
+// detailAliases maps commonly used field names in savegames to our detail keys
+var detailAliases = map[string]string{
+	"name":           detailCharacter,
+	"player":         detailCharacter,
+	"player_name":    detailCharacter,
+	"playername":     detailCharacter,
+	"character":      detailCharacter,
+	"character_name": detailCharacter,
+	"charactername":  detailCharacter,
+	"hero":           detailCharacter,
+	"level":          detailLevel,
+	"lvl":            detailLevel,
+	"player_level":   detailLevel,
+	"playerlevel":    detailLevel,
+	"playtime":       detailPlaytime,
+	"play_time":      detailPlaytime,
+	"time_played":    detailPlaytime,
+	"timeplayed":     detailPlaytime,
+	"location":       detailLocation,
+	"area":           detailLocation,
+	"map":            detailLocation,
+	"map_name":       detailLocation,
+	"scene":          detailLocation,
+	"current_scene":  detailLocation,
+	"zone":           detailLocation,
+	"room":           detailLocation,
+}
+
+// extractKnownFields picks out the fields we recognize from a generic dictionary, looking one
+// level deep into nested dictionaries as games tend to group e.g. player data together
+func extractKnownFields(values map[string]interface{}) map[string]string {
+	details := map[string]string{}
+	collectKnownFields(values, details, true)
+	return details
+}
+
+func collectKnownFields(values map[string]interface{}, details map[string]string, recurse bool) {
+	for key, value := range values {
+		detail, ok := detailAliases[strings.ToLower(key)]
+		if ok {
+			if _, exists := details[detail]; !exists {
+				if str := scalarToString(value); str != "" {
+					details[detail] = str
+				}
+			}
+		}
+
+		if nested, isMap := value.(map[string]interface{}); isMap && recurse {
+			collectKnownFields(nested, details, false)
+		}
+	}
+}
+
+func scalarToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// formatPlaytime formats a duration in seconds as h:mm:ss
+func formatPlaytime(seconds int64) string {
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, (seconds/60)%60, seconds%60)
+}
+
+// godotJSONInspector reads Godot games that save their state as a JSON dictionary
+type godotJSONInspector struct{}
+
+func (godotJSONInspector) Name() string {
+	return "godot-json"
+}
+
+func (godotJSONInspector) Matches(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".json" || ext == ".save" || ext == ".sav"
+}
+
+func (godotJSONInspector) Inspect(filePath string) (map[string]string, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	err = json.Unmarshal(contents, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractKnownFields(values), nil
+}
+
+// godotResourceInspector reads Godot text resources (.tres) used as savegames
+type godotResourceInspector struct{}
+
+func (godotResourceInspector) Name() string {
+	return "godot-tres"
+}
+
+func (godotResourceInspector) Matches(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".tres"
+}
+
+func (godotResourceInspector) Inspect(filePath string) (map[string]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// Only the properties in the [resource] section are interesting, the rest is references
+	values := map[string]interface{}{}
+	inResource := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inResource = line == "[resource]"
+			continue
+		}
+
+		if !inResource {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return extractKnownFields(values), nil
+}
+
+// renpyInspector reads Ren'Py saves, which are zip files with a JSON description inside
+type renpyInspector struct{}
+
+func (renpyInspector) Name() string {
+	return "renpy"
+}
+
+func (renpyInspector) Matches(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".save"
+}
+
+func (renpyInspector) Inspect(filePath string) (map[string]string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = archive.Close()
+	}()
+
+	details := map[string]string{}
+	for _, file := range archive.File {
+		switch file.Name {
+		case "json":
+			contents, err := readZipFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			values := map[string]interface{}{}
+			err = json.Unmarshal(contents, &values)
+			if err != nil {
+				return nil, err
+			}
+
+			if name, ok := values["_save_name"].(string); ok && name != "" {
+				details[detailSaveName] = name
+			}
+			if runtime, ok := values["_game_runtime"].(float64); ok {
+				details[detailPlaytime] = formatPlaytime(int64(runtime))
+			}
+
+		case "extra_info":
+			// Older Ren'Py versions only store the save name here
+			if _, exists := details[detailSaveName]; exists {
+				continue
+			}
+
+			contents, err := readZipFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			if name := strings.TrimSpace(string(contents)); name != "" {
+				details[detailSaveName] = name
+			}
+		}
+	}
+
+	return details, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return io.ReadAll(reader)
+}
+
+// rpgMakerInspector reads RPG Maker MV (.rpgsave) and MZ (.rmmzsave) savegames
+type rpgMakerInspector struct{}
+
+func (rpgMakerInspector) Name() string {
+	return "rpgmaker"
+}
+
+func (rpgMakerInspector) Matches(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".rpgsave" || ext == ".rmmzsave"
+}
+
+func (rpgMakerInspector) Inspect(filePath string) (map[string]string, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if strings.ToLower(filepath.Ext(filePath)) == ".rmmzsave" {
+		// MZ compresses the JSON with zlib
+		reader, err := zlib.NewReader(bytes.NewReader(contents))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// MV stores the JSON with LZString.compressToBase64
+		decompressed, err := lzStringDecompressFromBase64(strings.TrimSpace(string(contents)))
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(decompressed)
+	}
+
+	save := map[string]interface{}{}
+	err = json.Unmarshal(data, &save)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{}
+
+	if system, ok := save["system"].(map[string]interface{}); ok {
+		if frames, ok := system["_framesOnSave"].(float64); ok {
+			// The games run at a fixed 60 frames per second
+			details[detailPlaytime] = formatPlaytime(int64(frames) / 60)
+		}
+	}
+
+	if gameMap, ok := save["map"].(map[string]interface{}); ok {
+		if mapID, ok := gameMap["_mapId"].(float64); ok {
+			details[detailLocation] = fmt.Sprintf("Map %03d", int64(mapID))
+		}
+	}
+
+	if leader, ok := rpgMakerPartyLeader(save); ok {
+		if name, ok := leader["_name"].(string); ok && name != "" {
+			details[detailCharacter] = name
+		}
+		if level, ok := leader["_level"].(float64); ok {
+			details[detailLevel] = strconv.FormatInt(int64(level), 10)
+		}
+	}
+
+	return details, nil
+}
+
+// rpgMakerPartyLeader finds the actor leading the party, which is the first actor in the party. The
+// actors are referred to by their index in the actor data.
+func rpgMakerPartyLeader(save map[string]interface{}) (map[string]interface{}, bool) {
+	party, ok := save["party"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	actors, ok := save["actors"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	partyActors := rpgMakerArray(party["_actors"])
+	actorData := rpgMakerArray(actors["_data"])
+	if len(partyActors) == 0 {
+		return nil, false
+	}
+
+	leaderID, ok := partyActors[0].(float64)
+	if !ok || leaderID < 0 || leaderID != math.Trunc(leaderID) || leaderID >= float64(len(actorData)) {
+		return nil, false
+	}
+
+	leader, ok := actorData[int(leaderID)].(map[string]interface{})
+	return leader, ok
+}
+
+// rpgMakerArray unwraps arrays that RPG Maker's JsonEx may have stored as {"@a": [...]}
+func rpgMakerArray(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		if arr, ok := v["@a"].([]interface{}); ok {
+			return arr
+		}
+	}
+	return nil
+}
+
+// lzStringMaxBits limits the dictionary to what fits in an int on every platform
+const lzStringMaxBits = 30
+
+const lzStringBase64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+// lzStringDecompressFromBase64 is a port of LZString.decompressFromBase64 used by RPG Maker MV
+func lzStringDecompressFromBase64(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	values := make([]int, len(input))
+	for i, c := range input {
+		idx := strings.IndexRune(lzStringBase64Alphabet, c)
+		if idx < 0 {
+			return "", fmt.Errorf("invalid LZString base64 character %q", c)
+		}
+		values[i] = idx
+	}
+
+	const resetValue = 32
+	position := resetValue
+	index := 0
+	val := values[0]
+	index++
+
+	readBits := func(numBits int) int {
+		bits := 0
+		power := 1
+		maxPower := 1 << numBits
+		for power != maxPower {
+			resb := val & position
+			position >>= 1
+			if position == 0 {
+				// Reading past the end yields zeroes, same as in the JavaScript version
+				position = resetValue
+				val = 0
+				if index < len(values) {
+					val = values[index]
+				}
+				index++
+			}
+			if resb > 0 {
+				bits |= power
+			}
+			power <<= 1
+		}
+		return bits
+	}
+
+	dictionary := map[int][]uint16{}
+	enlargeIn := 4
+	dictSize := 4
+	numBits := 3
+
+	var c []uint16
+	switch next := readBits(2); next {
+	case 0, 1:
+		c = []uint16{uint16(readBits(8 << next))}
+	case 2:
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid LZString data")
+	}
+
+	dictionary[3] = c
+	w := c
+	result := append([]uint16{}, c...)
+
+	for {
+		if index > len(values) {
+			return "", fmt.Errorf("unexpected end of LZString data")
+		}
+
+		// Corrupted data can make the dictionary grow past anything a real save would need
+		if numBits > lzStringMaxBits {
+			return "", fmt.Errorf("invalid LZString data")
+		}
+
+		cc := readBits(numBits)
+
+		switch cc {
+		case 0, 1:
+			dictionary[dictSize] = []uint16{uint16(readBits(8 << cc))}
+			cc = dictSize
+			dictSize++
+			enlargeIn--
+		case 2:
+			return string(utf16.Decode(result)), nil
+		}
+
+		if enlargeIn == 0 {
+			enlargeIn = 1 << numBits
+			numBits++
+		}
+
+		var entry []uint16
+		if existing, ok := dictionary[cc]; ok {
+			entry = existing
+		} else if cc == dictSize && len(w) > 0 {
+			entry = append(append([]uint16{}, w...), w[0])
+		}
+		if len(entry) == 0 || len(w) == 0 {
+			return "", fmt.Errorf("invalid LZString data")
+		}
+		result = append(result, entry...)
+
+		dictionary[dictSize] = append(append([]uint16{}, w...), entry[0])
+		dictSize++
+		enlargeIn--
+
+		w = entry
+
+		if enlargeIn == 0 {
+			enlargeIn = 1 << numBits
+			numBits++
+		}
+	}
+}
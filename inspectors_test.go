@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLZStringDecompressFromBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", input: "", want: ""},
+		{name: "short", input: "BYUwNmD2Q===", want: "hello"},
+		{name: "repeated", input: "IYI17Sg=", want: "abababababab"},
+		{name: "json", input: "N4IghiBc5rfw+IC+Q===", want: `{"a":"aaaaaaaaaaaaaaaaaaaa"}`},
+		{name: "wide characters", input: "BYS4NmD2AEjAZEA=", want: "héllo ☃"},
+		{name: "invalid character", input: "BYU*NmD2Q", wantErr: true},
+		{name: "invalid first entry", input: "w", wantErr: true},
+		{name: "truncated", input: "B", wantErr: true},
+		{name: "end of data right away", input: "Q", want: ""},
+		{name: "reference past the dictionary", input: "BYU//////", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := lzStringDecompressFromBase64(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRPGMakerInspectorPartyLeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		save      string
+		character string
+		level     string
+	}{
+		{
+			name:      "leader",
+			save:      `{"party":{"_actors":[1]},"actors":{"_data":[null,{"_name":"Harold","_level":7}]}}`,
+			character: "Harold",
+			level:     "7",
+		},
+		{
+			name:      "wrapped arrays",
+			save:      `{"party":{"_actors":{"@a":[1]}},"actors":{"_data":{"@a":[null,{"_name":"Therese","_level":3}]}}}`,
+			character: "Therese",
+			level:     "3",
+		},
+		{name: "negative leader", save: `{"party":{"_actors":[-1]},"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "fractional leader", save: `{"party":{"_actors":[0.5]},"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "leader past the actors", save: `{"party":{"_actors":[2]},"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "leader is not a number", save: `{"party":{"_actors":["1"]},"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "empty party", save: `{"party":{"_actors":[]},"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "party is not an object", save: `{"party":[1],"actors":{"_data":[{"_name":"Harold"}]}}`},
+		{name: "no actors", save: `{"party":{"_actors":[0]}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var compressed bytes.Buffer
+			writer := zlib.NewWriter(&compressed)
+			if _, err := writer.Write([]byte(test.save)); err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), "file1.rmmzsave")
+			if err := os.WriteFile(path, compressed.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			details, err := rpgMakerInspector{}.Inspect(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if details[detailCharacter] != test.character {
+				t.Errorf("got character %q, want %q", details[detailCharacter], test.character)
+			}
+			if details[detailLevel] != test.level {
+				t.Errorf("got level %q, want %q", details[detailLevel], test.level)
+			}
+		})
+	}
+}
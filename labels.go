@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Captured parts of the file name are used in labels as {name}
+var labelVariableRegexp = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// saveLabel is a compiled label pattern of a rule
+type saveLabel struct {
+	pattern *regexp.Regexp
+	label   string
+}
+
+// compileSaveLabels compiles the label patterns of the rule, skipping the ones that can't be parsed
+func (a *App) compileSaveLabels(rule ActiveRule) []saveLabel {
+	labels := []saveLabel{}
+	for _, label := range rule.Platform.Labels {
+		// Windows file names are case-insensitive
+		pattern, err := captureRegexpFor(label.Pattern, rule.ViaWine)
+		if err != nil {
+			a.ReportError(fmt.Errorf("failed to parse label pattern %s from %s.yaml: %w", label.Pattern, rule.RuleFilename, err))
+			continue
+		}
+
+		labels = append(labels, saveLabel{
+			pattern: pattern,
+			label:   label.Label,
+		})
+	}
+
+	return labels
+}
+
+// saveLabel names the file the way the game does, using the first label pattern matching the name of
+// the file. Captured numbers lose their leading zeroes, so slot_003.dat can be "Slot 3".
+func (rule ActiveRule) saveLabel(path string) string {
+	name := filepath.Base(path)
+	for _, label := range rule.saveLabels {
+		match := label.pattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		captures := map[string]string{}
+		for i, captureName := range label.pattern.SubexpNames() {
+			if captureName != "" {
+				captures[captureName] = trimLeadingZeroes(match[i])
+			}
+		}
+
+		return labelVariableRegexp.ReplaceAllStringFunc(label.label, func(variable string) string {
+			if value, ok := captures[labelVariableRegexp.FindStringSubmatch(variable)[1]]; ok {
+				return value
+			}
+			return variable
+		})
+	}
+
+	return ""
+}
+
+// trimLeadingZeroes turns e.g. 003 into 3, leaving anything that's not a number alone
+func trimLeadingZeroes(value string) string {
+	if value == "" || strings.Trim(value, "0123456789") != "" {
+		return value
+	}
+
+	trimmed := strings.TrimLeft(value, "0")
+	if trimmed == "" {
+		return "0"
+	}
+	return trimmed
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Game launchers we know how to read the installed games from
+const (
+	launcherHeroic  = "heroic"
+	launcherLutris  = "lutris"
+	launcherBottles = "bottles"
+)
+
+// InstalledGame is a game installed via one of the launchers
+type InstalledGame struct {
+	Launcher   string `json:"launcher"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	InstallDir string `json:"installDir"`
+	Executable string `json:"executable"`
+	Prefix     string `json:"prefix"`
+}
+
+// Lutris game configs are named {slug}-{timestamp}.yml
+var lutrisConfigRegexp = regexp.MustCompile(`^(.+)-\d+\.yml$`)
+
+// findInstalledGames reads the games installed via Heroic, Lutris, and Bottles
+func (a *App) findInstalledGames() []InstalledGame {
+	games := []InstalledGame{}
+	games = append(games, a.findHeroicGames()...)
+	games = append(games, a.findLutrisGames()...)
+	games = append(games, a.findBottlesGames()...)
+
+	sort.SliceStable(games, func(i, j int) bool {
+		if games[i].Launcher != games[j].Launcher {
+			return games[i].Launcher < games[j].Launcher
+		}
+		return games[i].ID < games[j].ID
+	})
+
+	return games
+}
+
+// readJSONFile reads the JSON file into the value, and tells if it existed and was valid
+func (a *App) readJSONFile(filePath string, value interface{}) bool {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+
+	err = json.Unmarshal(contents, value)
+	if err != nil {
+		a.ReportError(fmt.Errorf("error parsing %s: %w", filePath, err))
+		return false
+	}
+
+	return true
+}
+
+// readYAMLFile reads the YAML file into the value, and tells if it existed and was valid
+func (a *App) readYAMLFile(filePath string, value interface{}) bool {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+
+	err = yaml.Unmarshal(contents, value)
+	if err != nil {
+		a.ReportError(fmt.Errorf("error parsing %s: %w", filePath, err))
+		return false
+	}
+
+	return true
+}
+
+func (a *App) findHeroicGames() []InstalledGame {
+	games := []InstalledGame{}
+
+	configDirs := []string{
+		filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "heroic"),
+		filepath.Join(os.Getenv("HOME"), ".var", "app", "com.heroicgameslauncher.hgl", "config", "heroic"),
+	}
+
+	for _, configDir := range configDirs {
+		// GOG games only have their titles in the library cache
+		gogLibrary := struct {
+			Games []struct {
+				AppName string `json:"app_name"`
+				Title   string `json:"title"`
+			} `json:"games"`
+		}{}
+		a.readJSONFile(filepath.Join(configDir, "store_cache", "gog_library.json"), &gogLibrary)
+
+		titles := map[string]string{}
+		for _, game := range gogLibrary.Games {
+			titles[game.AppName] = game.Title
+		}
+
+		gogInstalled := struct {
+			Installed []struct {
+				AppName     string `json:"appName"`
+				InstallPath string `json:"install_path"`
+			} `json:"installed"`
+		}{}
+		a.readJSONFile(filepath.Join(configDir, "gog_store", "installed.json"), &gogInstalled)
+
+		for _, game := range gogInstalled.Installed {
+			title := titles[game.AppName]
+			if title == "" {
+				title = filepath.Base(game.InstallPath)
+			}
+
+			games = append(games, InstalledGame{
+				Launcher:   launcherHeroic,
+				ID:         game.AppName,
+				Name:       title,
+				InstallDir: game.InstallPath,
+				Prefix:     a.heroicWinePrefix(configDir, game.AppName),
+			})
+		}
+
+		// Epic games are installed via Legendary
+		epicInstalled := map[string]struct {
+			AppName     string `json:"app_name"`
+			Title       string `json:"title"`
+			InstallPath string `json:"install_path"`
+			Executable  string `json:"executable"`
+		}{}
+		a.readJSONFile(filepath.Join(configDir, "legendaryConfig", "legendary", "installed.json"), &epicInstalled)
+
+		for appName, game := range epicInstalled {
+			executable := ""
+			if game.Executable != "" {
+				executable = filepath.Join(game.InstallPath, game.Executable)
+			}
+
+			games = append(games, InstalledGame{
+				Launcher:   launcherHeroic,
+				ID:         appName,
+				Name:       game.Title,
+				InstallDir: game.InstallPath,
+				Executable: executable,
+				Prefix:     a.heroicWinePrefix(configDir, appName),
+			})
+		}
+	}
+
+	return games
+}
+
+// heroicWinePrefix reads the Wine prefix from the game's settings, if it has one
+func (a *App) heroicWinePrefix(configDir string, appName string) string {
+	settings := map[string]json.RawMessage{}
+	if !a.readJSONFile(filepath.Join(configDir, "GamesConfig", fmt.Sprintf("%s.json", appName)), &settings) {
+		return ""
+	}
+
+	gameSettings := struct {
+		WinePrefix string `json:"winePrefix"`
+	}{}
+	if err := json.Unmarshal(settings[appName], &gameSettings); err != nil {
+		return ""
+	}
+
+	return gameSettings.WinePrefix
+}
+
+func (a *App) findLutrisGames() []InstalledGame {
+	games := []InstalledGame{}
+
+	// Newer versions keep the configs under the data directory
+	gamesDirs := []string{
+		filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "lutris", "games"),
+		filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "lutris", "games"),
+		filepath.Join(os.Getenv("HOME"), ".var", "app", "net.lutris.Lutris", "config", "lutris", "games"),
+		filepath.Join(os.Getenv("HOME"), ".var", "app", "net.lutris.Lutris", "data", "lutris", "games"),
+	}
+
+	for _, gamesDir := range gamesDirs {
+		configFiles, err := filepath.Glob(filepath.Join(gamesDir, "*.yml"))
+		if err != nil {
+			continue
+		}
+
+		for _, configFile := range configFiles {
+			config := struct {
+				Game struct {
+					Exe        string `yaml:"exe"`
+					Prefix     string `yaml:"prefix"`
+					WorkingDir string `yaml:"working_dir"`
+				} `yaml:"game"`
+			}{}
+			if !a.readYAMLFile(configFile, &config) {
+				continue
+			}
+
+			slug := strings.TrimSuffix(filepath.Base(configFile), ".yml")
+			if match := lutrisConfigRegexp.FindStringSubmatch(filepath.Base(configFile)); match != nil {
+				slug = match[1]
+			}
+
+			// Wine games can have the executable relative to the prefix
+			executable := config.Game.Exe
+			if executable != "" && !filepath.IsAbs(executable) && config.Game.Prefix != "" {
+				executable = filepath.Join(config.Game.Prefix, executable)
+			}
+
+			installDir := config.Game.WorkingDir
+			if installDir == "" && executable != "" {
+				installDir = filepath.Dir(executable)
+			}
+
+			games = append(games, InstalledGame{
+				Launcher:   launcherLutris,
+				ID:         slug,
+				Name:       strings.ReplaceAll(slug, "-", " "),
+				InstallDir: installDir,
+				Executable: executable,
+				Prefix:     config.Game.Prefix,
+			})
+		}
+	}
+
+	return games
+}
+
+func (a *App) findBottlesGames() []InstalledGame {
+	games := []InstalledGame{}
+
+	bottlesDirs := []string{
+		filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "bottles", "bottles"),
+		filepath.Join(os.Getenv("HOME"), ".var", "app", "com.usebottles.bottles", "data", "bottles", "bottles"),
+	}
+
+	for _, bottlesDir := range bottlesDirs {
+		bottleFiles, err := filepath.Glob(filepath.Join(bottlesDir, "*", "bottle.yml"))
+		if err != nil {
+			continue
+		}
+
+		for _, bottleFile := range bottleFiles {
+			bottle := struct {
+				Name             string `yaml:"Name"`
+				ExternalPrograms map[string]struct {
+					Name string `yaml:"name"`
+					Path string `yaml:"path"`
+				} `yaml:"External_Programs"`
+			}{}
+			if !a.readYAMLFile(bottleFile, &bottle) {
+				continue
+			}
+
+			// Each program added to the bottle is most likely a game
+			for _, program := range bottle.ExternalPrograms {
+				games = append(games, InstalledGame{
+					Launcher:   launcherBottles,
+					ID:         program.Name,
+					Name:       program.Name,
+					InstallDir: filepath.Dir(program.Path),
+					Executable: program.Path,
+					Prefix:     filepath.Dir(bottleFile),
+				})
+			}
+		}
+	}
+
+	return games
+}
+
+// launcherVariable returns the value of a launcher variable used in rules, and tells if the name
+// was one, e.g. HEROIC_INSTALL_DIR:<app name> or LUTRIS_PREFIX:<slug>
+func (a *App) launcherVariable(name string) (string, bool) {
+	variable, id, ok := strings.Cut(name, ":")
+	if !ok {
+		return "", false
+	}
+
+	launcher, field, ok := strings.Cut(variable, "_")
+	if !ok {
+		return "", false
+	}
+
+	launcher = strings.ToLower(launcher)
+	if launcher != launcherHeroic && launcher != launcherLutris && launcher != launcherBottles {
+		return "", false
+	}
+
+	if field != "INSTALL_DIR" && field != "PREFIX" {
+		return "", false
+	}
+
+	for _, game := range a.installedGames {
+		if game.Launcher != launcher || game.ID != id {
+			continue
+		}
+
+		if field == "PREFIX" {
+			return game.Prefix, true
+		}
+		return game.InstallDir, true
+	}
+
+	// Not installed, so the pattern can't match anything
+	return "", true
+}
+
+// normalizeGameName makes game names comparable, e.g. "Baldur's Gate 2" and "baldurs-gate-2"
+func normalizeGameName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// findInstalledGame finds the installed game the rule is most likely for, by its executable or name
+func (a *App) findInstalledGame(rule ActiveRule) *InstalledGame {
+	for _, game := range a.installedGames {
+		if game.Executable == "" {
+			continue
+		}
+
+		proc := ProcessInfo{
+			Exe:     game.Executable,
+			Name:    filepath.Base(game.Executable),
+			Cmdline: game.Executable,
+		}
+		if _, ok := rule.matchProcess(proc); ok {
+			return &game
+		}
+	}
+
+	name := normalizeGameName(rule.Name)
+	for _, game := range a.installedGames {
+		if name != "" && normalizeGameName(game.Name) == name {
+			return &game
+		}
+	}
+
+	return nil
+}
+
+// winePrefixCandidates lists the Wine prefixes of the installed games
+func winePrefixCandidates(games []InstalledGame) []string {
+	prefixes := []string{}
+	for _, game := range games {
+		if game.Prefix != "" {
+			prefixes = append(prefixes, game.Prefix)
+		}
+	}
+	return prefixes
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +13,11 @@ import (
 var assets embed.FS
 
 func main() {
+	// Launch wrapper mode, e.g. `baacup run %command%` as a Steam launch option
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runWrapper(os.Args[2:]))
+	}
+
 	// Create an instance of the app structure
 	app := newApp()
 
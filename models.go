@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"regexp"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/gobwas/glob"
 )
 
 // BackupConfig stores configuration for how to handle backups
 type BackupConfig struct {
-	KeepSaves    int   `yaml:"keep_saves" json:"keepSaves"`
-	MaxMBPerGame int64 `yaml:"max_mb_per_game" json:"maxMBPerGame"`
+	KeepSaves            int   `yaml:"keep_saves" json:"keepSaves"`
+	KeepConfigs          int   `yaml:"keep_configs" json:"keepConfigs"`
+	MaxMBPerGame         int64 `yaml:"max_mb_per_game" json:"maxMBPerGame"`
+	KeepSessionSnapshots int   `yaml:"keep_session_snapshots" json:"keepSessionSnapshots"`
 }
 
 // CompactionConfig stores configuration for how to handle backups when they enter a state for compaction
@@ -19,20 +25,49 @@ type CompactionConfig struct {
 	CompactAfterDays int `yaml:"compact_after_days" json:"compactAfterDays"`
 }
 
+// SweepConfig stores configuration for how to check for changes to games that are not running
+type SweepConfig struct {
+	IntervalMinutes int `yaml:"interval_minutes" json:"intervalMinutes"`
+}
+
+// RuleConfig stores the user's own settings for a rule, on top of the rule file
+type RuleConfig struct {
+	Exclude []string `yaml:"exclude" json:"exclude"`
+}
+
 // Config stores application configuration
 type Config struct {
-	DisabledRules    []string          `yaml:"disabled_rules" json:"disabledRules"`
-	PathSeparator    string            `json:"pathSeparator"`
-	Backups          *BackupConfig     `yaml:"backups" json:"backups"`
-	Compaction       *CompactionConfig `yaml:"compaction" json:"compaction"`
-	RulesLastUpdated time.Time         `yaml:"rules_last_updated" json:"rulesLastUpdated"`
-	RulesAutoUpdate  bool              `yaml:"rules_auto_update" json:"rulesAutoUpdate"`
+	DisabledRules    []string               `yaml:"disabled_rules" json:"disabledRules"`
+	PathSeparator    string                 `json:"pathSeparator"`
+	Backups          *BackupConfig          `yaml:"backups" json:"backups"`
+	Compaction       *CompactionConfig      `yaml:"compaction" json:"compaction"`
+	Sweeps           *SweepConfig           `yaml:"sweeps" json:"sweeps"`
+	Rules            map[string]*RuleConfig `yaml:"rules" json:"rules"`
+	RulesLastUpdated time.Time              `yaml:"rules_last_updated" json:"rulesLastUpdated"`
+	RulesAutoUpdate  bool                   `yaml:"rules_auto_update" json:"rulesAutoUpdate"`
+}
+
+// SaveLabel names the files matching the pattern, e.g. "Slot {n}" for slot_<n>.dat
+type SaveLabel struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Label   string `yaml:"label" json:"label"`
 }
 
 // RulePlatform is the "platform" section of a rule
 type RulePlatform struct {
-	Executable string   `yaml:"executable" json:"executable"`
-	Savegames  []string `yaml:"savegames" json:"savegames"`
+	Executable       string      `yaml:"executable" json:"executable"`
+	Executables      []string    `yaml:"executables" json:"executables"`
+	Cmdline          string      `yaml:"cmdline" json:"cmdline"`
+	ProcessName      string      `yaml:"process_name" json:"processName"`
+	ExcludeProcesses []string    `yaml:"exclude_processes" json:"excludeProcesses"`
+	SteamAppID       string      `yaml:"steam_appid" json:"steamAppId"`
+	Flatpaks         []string    `yaml:"flatpaks" json:"flatpaks"`
+	Snaps            []string    `yaml:"snaps" json:"snaps"`
+	Savegames        []string    `yaml:"savegames" json:"savegames"`
+	Configs          []string    `yaml:"configs" json:"configs"`
+	Exclude          []string    `yaml:"exclude" json:"exclude"`
+	Labels           []SaveLabel `yaml:"labels" json:"labels"`
+	Thumbnail        string      `yaml:"thumbnail" json:"thumbnail"`
 }
 
 // Rule for how to manage a game
@@ -40,30 +75,116 @@ type Rule struct {
 	Name      string                  `yaml:"name" json:"name"`
 	Issues    string                  `yaml:"issues" json:"issues"`
 	Platforms map[string]RulePlatform `yaml:"platforms" json:"platforms"`
+
+	// Template the rule is based on, and the values of its ${param.name} variables
+	Extends string            `yaml:"extends" json:"extends"`
+	Params  map[string]string `yaml:"params" json:"params"`
 }
 
 // ActiveRule is a game rule for a game that has been detected as running
 type ActiveRule struct {
-	RuleFilename   string       `json:"ruleFilename"`
-	Name           string       `json:"name"`
-	Issues         string       `yaml:"issues" json:"issues"`
-	Platform       RulePlatform `json:"platform"`
-	executableGlob glob.Glob
+	RuleFilename string       `json:"ruleFilename"`
+	Name         string       `json:"name"`
+	Issues       string       `yaml:"issues" json:"issues"`
+	Platform     RulePlatform `json:"platform"`
+
+	// Windows rule used for games running via Wine or Proton on Linux
+	ViaWine bool `json:"viaWine"`
+
+	// Disabled in the config, so the game is not monitored or backed up
+	Disabled bool `json:"disabled"`
+
+	// What detected the game as running, and the process
+	DetectedBy      string `json:"detectedBy"`
+	DetectedProcess string `json:"detectedProcess"`
+
+	// The game installed via Heroic, Lutris, or Bottles the rule seems to be for
+	Installed *InstalledGame `json:"installed"`
+
+	// Named parts captured from the process, used as ${match.name} in savegame patterns
+	Captures map[string]string `json:"captures"`
+
+	// Install folder of the game, used as ${GAME_DIR} in savegame patterns
+	GameDir string `json:"gameDir"`
+
+	processMatchers     []processMatcher
+	excludeProcessGlobs []glob.Glob
+	savegameTemplates   []string
+	configTemplates     []string
+	excludeTemplates    []string
+	thumbnailTemplates  []string
+	saveLabels          []saveLabel
+}
+
+// Kinds of process matchers
+const (
+	matchExecutable     = "executable"
+	matchCmdline        = "cmdline"
+	matchProcessName    = "process_name"
+	matchWineExecutable = "wine executable"
+	matchWrapper        = "baacup run"
+	matchSteamApp       = "steam app"
+)
+
+// processMatcher is one of the alternative ways to detect the game from running processes
+type processMatcher struct {
+	kind     string
+	pattern  string
+	glob     glob.Glob
+	captures *regexp.Regexp
+}
+
+func (m processMatcher) String() string {
+	if m.pattern == "" {
+		return m.kind
+	}
+	return fmt.Sprintf("%s %s", m.kind, m.pattern)
+}
+
+// ProcessInfo is what we know of a running process for detecting games
+type ProcessInfo struct {
+	Exe     string `json:"exe"`
+	Name    string `json:"name"`
+	Cmdline string `json:"cmdline"`
 }
 
 // BackupMetadata stores the metadata for a backed up savefile
 type BackupMetadata struct {
-	FileSize     int64     `json:"fileSize" yaml:"-"`
-	Filename     string    `json:"filename" yaml:"-"`
-	Source       string    `yaml:"source" json:"source"`
-	BackupTime   time.Time `yaml:"backup_time" json:"backupTime"`
-	LastModified time.Time `yaml:"last_modified" json:"lastModified"`
+	FileSize     int64             `json:"fileSize" yaml:"-"`
+	Filename     string            `json:"filename" yaml:"-"`
+	Source       string            `yaml:"source" json:"source"`
+	Category     string            `yaml:"category" json:"category"`
+	Label        string            `yaml:"label,omitempty" json:"label"`
+	BackupTime   time.Time         `yaml:"backup_time" json:"backupTime"`
+	LastModified time.Time         `yaml:"last_modified" json:"lastModified"`
+	Reason       string            `yaml:"reason,omitempty" json:"reason"`
+	Thumbnail    string            `yaml:"thumbnail,omitempty" json:"thumbnail"`
+	Inspector    string            `yaml:"inspector,omitempty" json:"inspector"`
+	Details      map[string]string `yaml:"details,omitempty" json:"details"`
+}
+
+// PlaySession is a single session of playing a game, as detected by Baacup
+type PlaySession struct {
+	ID       string    `yaml:"id" json:"id"`
+	Start    time.Time `yaml:"start" json:"start"`
+	End      time.Time `yaml:"end" json:"end"`
+	Duration int64     `yaml:"-" json:"duration"`
+	Backups  []string  `yaml:"backups" json:"backups"`
+}
+
+// PauseState tells if monitoring is paused for all the games, or for some of them. Pauses without an
+// end last until resumed.
+type PauseState struct {
+	Paused bool                  `json:"paused"`
+	Until  *time.Time            `json:"until"`
+	Rules  map[string]*time.Time `json:"rules"`
 }
 
 // Monitor information for what paths we're monitoring
 type Monitor struct {
-	Path         string `json:"path"`
-	RuleFilename string `json:"ruleFilename"`
+	Path         string   `json:"path"`
+	Exclude      []string `json:"exclude"`
+	RuleFilename string   `json:"ruleFilename"`
 }
 
 // App is the root application
@@ -72,11 +193,38 @@ type App struct {
 	Config         *Config                     `json:"config"`
 	Rules          map[string]ActiveRule       `json:"rules"`
 	Backups        map[string][]BackupMetadata `json:"activeBackups"`
+	Sessions       map[string][]PlaySession    `json:"sessions"`
 	ActiveMonitors []Monitor                   `json:"activeMonitors"`
 	ActiveRules    map[string]ActiveRule       `json:"activeRules"`
-	ProcessList    []string                    `json:"processList"`
+	ProcessList    []ProcessInfo               `json:"processList"`
 	BasePath       string                      `json:"basePath"`
 	Errors         []string                    `json:"errors"`
 	Events         []string                    `json:"events"`
 	exit           chan bool
+	lastSweep      time.Time
+	watcher        *fsnotify.Watcher
+	watchedDirs    map[string]bool
+	polledMonitors []Monitor
+	pendingChanges map[string]bool
+	activeSessions map[string]string
+	headless       bool
+	steam          *SteamInfo
+	installedGames []InstalledGame
+	sandboxes      []Sandbox
+	gameDirs       map[string]string
+
+	// Monitoring paused by the user, no automatic backups are made while paused
+	pauseState PauseState
+
+	// Steam app IDs Steam says are running
+	runningSteamApps map[string]bool
+
+	// Games launched via `baacup run`, and how it reaches us
+	wrappedRules     map[string]bool
+	wrapperRequests  chan wrapperRequest
+	instanceListener net.Listener
+
+	// Work from the UI run on the monitor goroutine
+	monitorRequests chan monitorRequest
+	monitorStopped  chan struct{}
 }
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// pauseEnd returns when a pause of the given length ends, or nil to pause until resumed
+func pauseEnd(minutes int) *time.Time {
+	if minutes <= 0 {
+		return nil
+	}
+
+	end := time.Now().Add(time.Duration(minutes) * time.Minute)
+	return &end
+}
+
+// describePause tells the user how long monitoring is paused for
+func describePause(until *time.Time) string {
+	if until == nil {
+		return "until resumed"
+	}
+	return fmt.Sprintf("until %s", until.Format("15:04"))
+}
+
+// isPaused checks if automatic backups of the game are paused, either for all games or just for it
+func (a *App) isPaused(ruleFilename string) bool {
+	if a.pauseState.Paused {
+		return true
+	}
+
+	_, paused := a.pauseState.Rules[ruleFilename]
+	return paused
+}
+
+// copyPauseState returns a copy of the pause state that is safe to hand to the UI
+func (a *App) copyPauseState() PauseState {
+	state := PauseState{
+		Paused: a.pauseState.Paused,
+		Until:  a.pauseState.Until,
+		Rules:  map[string]*time.Time{},
+	}
+	for ruleFilename, until := range a.pauseState.Rules {
+		state.Rules[ruleFilename] = until
+	}
+
+	return state
+}
+
+// pauseUpdated tells the UI what is paused
+func (a *App) pauseUpdated() {
+	a.emit("pausedUpdated", a.copyPauseState())
+}
+
+// Pause stops all automatic backups, for the given number of minutes or until resumed if 0
+func (a *App) Pause(minutes int) {
+	a.onMonitor(func() {
+		a.pauseAll(minutes)
+	})
+}
+
+func (a *App) pauseAll(minutes int) {
+	a.pauseState.Paused = true
+	a.pauseState.Until = pauseEnd(minutes)
+
+	a.AddEvent(fmt.Sprintf("Paused monitoring %s", describePause(a.pauseState.Until)))
+	a.pauseUpdated()
+}
+
+// Resume starts the automatic backups again, except for games paused on their own
+func (a *App) Resume() {
+	a.onMonitor(a.resumeAll)
+}
+
+func (a *App) resumeAll() {
+	if !a.pauseState.Paused {
+		return
+	}
+
+	a.pauseState.Paused = false
+	a.pauseState.Until = nil
+
+	a.AddEvent("Resumed monitoring")
+	a.pauseUpdated()
+
+	for ruleFilename := range a.ActiveRules {
+		a.catchUpAfterPause(ruleFilename)
+	}
+}
+
+// PauseRule stops the automatic backups of a game, for the given number of minutes or until resumed
+// if 0
+func (a *App) PauseRule(ruleFilename string, minutes int) bool {
+	ok := false
+	a.onMonitor(func() {
+		ok = a.pauseRule(ruleFilename, minutes)
+	})
+	return ok
+}
+
+func (a *App) pauseRule(ruleFilename string, minutes int) bool {
+	rule, ok := a.Rules[ruleFilename]
+	if !ok {
+		a.ReportError(fmt.Errorf("tried to pause %s but there is no such rule", ruleFilename))
+		return false
+	}
+
+	until := pauseEnd(minutes)
+	a.pauseState.Rules[ruleFilename] = until
+
+	a.AddEvent(fmt.Sprintf("Paused monitoring %s %s", rule.Name, describePause(until)))
+	a.pauseUpdated()
+
+	return true
+}
+
+// ResumeRule starts the automatic backups of a game again
+func (a *App) ResumeRule(ruleFilename string) bool {
+	ok := false
+	a.onMonitor(func() {
+		ok = a.resumeRule(ruleFilename)
+	})
+	return ok
+}
+
+func (a *App) resumeRule(ruleFilename string) bool {
+	if _, paused := a.pauseState.Rules[ruleFilename]; !paused {
+		return false
+	}
+
+	delete(a.pauseState.Rules, ruleFilename)
+
+	a.AddEvent(fmt.Sprintf("Resumed monitoring %s", a.Rules[ruleFilename].Name))
+	a.pauseUpdated()
+
+	a.catchUpAfterPause(ruleFilename)
+
+	return true
+}
+
+// GetPauseState returns what monitoring is paused
+func (a *App) GetPauseState() PauseState {
+	state := PauseState{}
+	a.onMonitor(func() {
+		state = a.copyPauseState()
+	})
+	return state
+}
+
+// checkPauses resumes the pauses that have run out
+func (a *App) checkPauses() {
+	now := time.Now()
+	if a.pauseState.Paused && a.pauseState.Until != nil && now.After(*a.pauseState.Until) {
+		a.resumeAll()
+	}
+
+	for ruleFilename, until := range a.pauseState.Rules {
+		if until != nil && now.After(*until) {
+			a.resumeRule(ruleFilename)
+		}
+	}
+}
+
+// catchUpAfterPause backs up what changed while the running game was paused, so the backups continue
+// from the current state of the savegames
+func (a *App) catchUpAfterPause(ruleFilename string) {
+	rule, running := a.ActiveRules[ruleFilename]
+	if !running || a.isPaused(ruleFilename) {
+		return
+	}
+
+	backedUp := a.backupChangedFiles(ruleFilename, rule, "")
+	if backedUp > 0 {
+		a.AddEvent(fmt.Sprintf("Backed up %d files of %s changed while paused", backedUp, rule.Name))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Sandbox is a Flatpak or Snap app's view of the user's folders
+type Sandbox struct {
+	ID         string `json:"id"`
+	Home       string `json:"home"`
+	DataHome   string `json:"dataHome"`
+	ConfigHome string `json:"configHome"`
+}
+
+// Savegame patterns under the default XDG directories, which sandboxes move elsewhere
+var (
+	homeDataRegexp   = regexp.MustCompile(`^\$(\{HOME\}|HOME)/\.local/share(/|$)`)
+	homeConfigRegexp = regexp.MustCompile(`^\$(\{HOME\}|HOME)/\.config(/|$)`)
+)
+
+// flatpakSandbox returns the folders of a Flatpak app. Flatpak moves the XDG directories, and e.g.
+// the Steam Flatpak also gives the games it runs a home of their own.
+func flatpakSandbox(appID string) Sandbox {
+	appDir := filepath.Join(os.Getenv("HOME"), ".var", "app", appID)
+	return Sandbox{
+		ID:         appID,
+		Home:       appDir,
+		DataHome:   filepath.Join(appDir, "data"),
+		ConfigHome: filepath.Join(appDir, "config"),
+	}
+}
+
+// snapSandbox returns the folders of a Snap, which gets a home of its own for each revision
+func snapSandbox(name string) Sandbox {
+	home := filepath.Join(os.Getenv("HOME"), "snap", name, "current")
+	return Sandbox{
+		ID:         name,
+		Home:       home,
+		DataHome:   filepath.Join(home, ".local", "share"),
+		ConfigHome: filepath.Join(home, ".config"),
+	}
+}
+
+// findSandboxes lists the Flatpak and Snap apps the user has run, which only exist on Linux
+func findSandboxes() []Sandbox {
+	sandboxes := []Sandbox{}
+	if getPlatform() != "linux" {
+		return sandboxes
+	}
+	home := os.Getenv("HOME")
+
+	flatpaks, err := os.ReadDir(filepath.Join(home, ".var", "app"))
+	if err == nil {
+		for _, flatpak := range flatpaks {
+			if flatpak.IsDir() {
+				sandboxes = append(sandboxes, flatpakSandbox(flatpak.Name()))
+			}
+		}
+	}
+
+	snaps, err := os.ReadDir(filepath.Join(home, "snap"))
+	if err == nil {
+		for _, snap := range snaps {
+			if _, err := os.Stat(filepath.Join(home, "snap", snap.Name(), "current")); err == nil {
+				sandboxes = append(sandboxes, snapSandbox(snap.Name()))
+			}
+		}
+	}
+
+	sort.Slice(sandboxes, func(i, j int) bool {
+		return sandboxes[i].Home < sandboxes[j].Home
+	})
+
+	return sandboxes
+}
+
+// sandboxPattern expands the savegame pattern as the sandboxed app would see it
+func (a *App) sandboxPattern(pattern string, sandbox Sandbox) (string, error) {
+	// Apps in the sandbox get their own XDG directories, even if they default to them via $HOME
+	pattern = homeDataRegexp.ReplaceAllString(pattern, "$${XDG_DATA_HOME}$2")
+	pattern = homeConfigRegexp.ReplaceAllString(pattern, "$${XDG_CONFIG_HOME}$2")
+
+	return expandVariables(pattern, func(name string) (string, error) {
+		switch name {
+		case "HOME":
+			return sandbox.Home, nil
+		case "XDG_DATA_HOME":
+			return sandbox.DataHome, nil
+		case "XDG_CONFIG_HOME":
+			return sandbox.ConfigHome, nil
+		}
+		return a.lookupRuleVariable(name)
+	})
+}
+
+// sandboxPatterns finds the savegame patterns for the Flatpak and Snap versions of the game. The
+// sandboxes the rule declares are always used, others only if the savegame folder exists in them.
+func (a *App) sandboxPatterns(platform RulePlatform, pattern string) []string {
+	if getPlatform() != "linux" {
+		return []string{}
+	}
+
+	declared := []Sandbox{}
+	for _, appID := range platform.Flatpaks {
+		declared = append(declared, flatpakSandbox(appID))
+	}
+	for _, name := range platform.Snaps {
+		declared = append(declared, snapSandbox(name))
+	}
+
+	// Patterns that don't depend on the user's folders are the same in every sandbox
+	expanded, _ := a.expandRuleVariables(pattern)
+	seen := map[string]bool{expanded: true}
+	patterns := []string{}
+
+	// Variables that can't be resolved have already been reported for the pattern itself
+	for _, sandbox := range declared {
+		sandboxed, err := a.sandboxPattern(pattern, sandbox)
+		if err == nil && !seen[sandboxed] {
+			seen[sandboxed] = true
+			patterns = append(patterns, sandboxed)
+		}
+	}
+
+	for _, sandbox := range a.sandboxes {
+		sandboxed, err := a.sandboxPattern(pattern, sandbox)
+		if err == nil && !seen[sandboxed] && len(watchDirs(applyCaptures([]string{sandboxed}, nil)[0])) > 0 {
+			seen[sandboxed] = true
+			patterns = append(patterns, sandboxed)
+		}
+	}
+
+	return patterns
+}
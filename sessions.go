@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+func (a *App) getSessionsPath() string {
+	return filepath.Join(a.BasePath, "sessions")
+}
+
+func (a *App) getSessionsFile(ruleFilename string) string {
+	return filepath.Join(a.getSessionsPath(), fmt.Sprintf("%s.yaml", ruleFilename))
+}
+
+func (a *App) loadSessions(ruleFilename string) []PlaySession {
+	sessions := []PlaySession{}
+	sessionsFile := a.getSessionsFile(ruleFilename)
+
+	contents, err := os.ReadFile(sessionsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.ReportError(err)
+		}
+		return sessions
+	}
+
+	err = yaml.Unmarshal(contents, &sessions)
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error parsing sessions from %s", sessionsFile))
+		return []PlaySession{}
+	}
+
+	for i := range sessions {
+		sessions[i].updateDuration()
+	}
+
+	return sessions
+}
+
+func (a *App) saveSessions(ruleFilename string) {
+	sessionsFile := a.getSessionsFile(ruleFilename)
+
+	data, err := yaml.Marshal(a.Sessions[ruleFilename])
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error writing sessions to %s", sessionsFile))
+		return
+	}
+
+	err = os.WriteFile(sessionsFile, data, 0o600)
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error writing sessions to %s", sessionsFile))
+		return
+	}
+
+	a.emit("sessionsUpdated", a.Sessions)
+}
+
+// updateDuration calculates the duration, sessions that were never finished e.g. due to a crash
+// don't have one
+func (s *PlaySession) updateDuration() {
+	if s.End.IsZero() {
+		s.Duration = 0
+		return
+	}
+	s.Duration = int64(s.End.Sub(s.Start).Seconds())
+}
+
+// activeSession returns the session currently in progress for the rule, if any
+func (a *App) activeSession(ruleFilename string) *PlaySession {
+	id, ok := a.activeSessions[ruleFilename]
+	if !ok {
+		return nil
+	}
+
+	sessions := a.Sessions[ruleFilename]
+	for i := range sessions {
+		if sessions[i].ID == id {
+			return &sessions[i]
+		}
+	}
+
+	return nil
+}
+
+func (a *App) startSession(rule ActiveRule) {
+	now := time.Now()
+	session := PlaySession{
+		ID:      now.Format("2006-01-02T150405.000"),
+		Start:   now,
+		Backups: []string{},
+	}
+
+	a.Sessions[rule.RuleFilename] = append(a.Sessions[rule.RuleFilename], session)
+	a.activeSessions[rule.RuleFilename] = session.ID
+	a.saveSessions(rule.RuleFilename)
+}
+
+func (a *App) endSession(rule ActiveRule) {
+	session := a.activeSession(rule.RuleFilename)
+	if session == nil {
+		return
+	}
+
+	session.End = time.Now()
+	session.updateDuration()
+	delete(a.activeSessions, rule.RuleFilename)
+	a.saveSessions(rule.RuleFilename)
+
+	a.AddEvent(fmt.Sprintf("Played %s for %s", rule.Name, formatPlaytime(session.Duration)))
+}
+
+// endAllSessions ends the sessions in progress, e.g. when we're shutting down
+func (a *App) endAllSessions() {
+	for ruleFilename := range a.activeSessions {
+		a.endSession(a.Rules[ruleFilename])
+	}
+}
+
+// addBackupToSession records the backup to the session in progress for the rule
+func (a *App) addBackupToSession(ruleFilename string, filename string) {
+	session := a.activeSession(ruleFilename)
+	if session == nil {
+		return
+	}
+
+	session.Backups = append(session.Backups, filename)
+	a.saveSessions(ruleFilename)
+}
+
+// GetSessions returns the play sessions of a game
+func (a *App) GetSessions(ruleFilename string) []PlaySession {
+	sessions := []PlaySession{}
+	a.onMonitor(func() {
+		// Copied, as the monitor goroutine keeps updating the sessions while the UI reads them
+		sessions = append(sessions, a.getSessions(ruleFilename)...)
+	})
+	return sessions
+}
+
+func (a *App) getSessions(ruleFilename string) []PlaySession {
+	sessions, ok := a.Sessions[ruleFilename]
+	if !ok {
+		return a.loadSessions(ruleFilename)
+	}
+	return sessions
+}
+
+// GetAllSessions returns the play sessions of all the games
+func (a *App) GetAllSessions() map[string][]PlaySession {
+	allSessions := map[string][]PlaySession{}
+	a.onMonitor(func() {
+		for ruleFilename, sessions := range a.Sessions {
+			allSessions[ruleFilename] = append([]PlaySession{}, sessions...)
+		}
+	})
+	return allSessions
+}
+
+// GetPlaytime returns the total time in seconds a game has been played while Baacup was running
+func (a *App) GetPlaytime(ruleFilename string) int64 {
+	var total int64
+	a.onMonitor(func() {
+		for _, session := range a.getSessions(ruleFilename) {
+			total += session.Duration
+		}
+	})
+	return total
+}
+
+// RestoreSession restores the savegames or configs of a game to how they were at the end of the
+// session, or both if the category is empty
+func (a *App) RestoreSession(ruleFilename string, sessionID string, category string) bool {
+	success := false
+	a.onMonitor(func() {
+		success = a.restoreSession(ruleFilename, sessionID, category)
+	})
+	return success
+}
+
+func (a *App) restoreSession(ruleFilename string, sessionID string, category string) bool {
+	var session *PlaySession
+	sessions := a.getSessions(ruleFilename)
+	for i := range sessions {
+		if sessions[i].ID == sessionID {
+			session = &sessions[i]
+		}
+	}
+
+	if session == nil {
+		a.ReportError(fmt.Errorf("tried to restore session %s but couldn't find it", sessionID))
+		return false
+	}
+
+	end := session.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	// Find the latest backup of each file that was made before the session ended
+	backups := a.latestBackups(ruleFilename, end, category)
+	if len(backups) == 0 {
+		a.ReportError(fmt.Errorf("no backups found from before the end of session %s", sessionID))
+		return false
+	}
+
+	return a.restoreBackups(ruleFilename, backups)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Difference between a 64-bit SteamID and the 32-bit account ID Steam uses in userdata paths
+const steamID64Base = 76561197960265728
+
+// SteamApp is a game installed in one of the Steam libraries
+type SteamApp struct {
+	AppID      string `json:"appId"`
+	Name       string `json:"name"`
+	InstallDir string `json:"installDir"`
+	Library    string `json:"library"`
+}
+
+// SteamUser is an account that has logged in to Steam on this machine
+type SteamUser struct {
+	AccountID   string `json:"accountId"`
+	AccountName string `json:"accountName"`
+	MostRecent  bool   `json:"mostRecent"`
+}
+
+// SteamInfo is what we found out about the Steam installation
+type SteamInfo struct {
+	Root      string              `json:"root"`
+	Libraries []string            `json:"libraries"`
+	Apps      map[string]SteamApp `json:"apps"`
+	Users     []SteamUser         `json:"users"`
+}
+
+// findSteam looks for the Steam installations, their libraries, installed games, and users
+func (a *App) findSteam() *SteamInfo {
+	steam := &SteamInfo{
+		Libraries: []string{},
+		Apps:      map[string]SteamApp{},
+		Users:     []SteamUser{},
+	}
+
+	seenRoots := map[string]bool{}
+	seenLibraries := map[string]bool{}
+	for _, candidate := range getSteamRoots() {
+		root, err := filepath.EvalSymlinks(candidate)
+		if err != nil || seenRoots[root] {
+			continue
+		}
+		seenRoots[root] = true
+
+		if steam.Root == "" {
+			steam.Root = root
+		}
+
+		for _, library := range a.findSteamLibraries(root) {
+			if !seenLibraries[library] {
+				seenLibraries[library] = true
+				steam.Libraries = append(steam.Libraries, library)
+			}
+		}
+
+		steam.Users = append(steam.Users, a.findSteamUsers(root)...)
+	}
+
+	for _, library := range steam.Libraries {
+		for _, app := range a.findSteamApps(library) {
+			steam.Apps[app.AppID] = app
+		}
+	}
+
+	return steam
+}
+
+// findSteamLibraries lists the library folders of the Steam installation, including itself
+func (a *App) findSteamLibraries(root string) []string {
+	libraries := []string{root}
+
+	libraryFolders := filepath.Join(root, "steamapps", "libraryfolders.vdf")
+	if _, err := os.Stat(libraryFolders); err != nil {
+		return libraries
+	}
+
+	vdf, err := readVDF(libraryFolders)
+	if err != nil {
+		a.ReportError(err)
+		return libraries
+	}
+
+	folders := vdf.node("libraryfolders")
+	keys := []string{}
+	for key := range folders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// Library folders are numbered, other keys are e.g. "contentstatsid"
+		if _, err := strconv.Atoi(key); err != nil {
+			continue
+		}
+
+		// Older versions of the file only have the path instead of an object
+		path := folders.str(key)
+		if path == "" {
+			path = folders.node(key).str("path")
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+		libraries = append(libraries, resolved)
+	}
+
+	return libraries
+}
+
+// findSteamApps reads the app manifests of the games installed in the library
+func (a *App) findSteamApps(library string) []SteamApp {
+	apps := []SteamApp{}
+
+	manifests, err := filepath.Glob(filepath.Join(library, "steamapps", "appmanifest_*.acf"))
+	if err != nil {
+		return apps
+	}
+
+	for _, manifest := range manifests {
+		vdf, err := readVDF(manifest)
+		if err != nil {
+			a.ReportError(err)
+			continue
+		}
+
+		state := vdf.node("AppState")
+		if state.str("appid") == "" {
+			continue
+		}
+
+		apps = append(apps, SteamApp{
+			AppID:      state.str("appid"),
+			Name:       state.str("name"),
+			InstallDir: state.str("installdir"),
+			Library:    library,
+		})
+	}
+
+	return apps
+}
+
+// findSteamUsers reads the accounts that have logged in to the Steam installation
+func (a *App) findSteamUsers(root string) []SteamUser {
+	users := []SteamUser{}
+
+	loginUsers := filepath.Join(root, "config", "loginusers.vdf")
+	if _, err := os.Stat(loginUsers); err != nil {
+		return users
+	}
+
+	vdf, err := readVDF(loginUsers)
+	if err != nil {
+		a.ReportError(err)
+		return users
+	}
+
+	for steamID, value := range vdf.node("users") {
+		user, ok := value.(vdfNode)
+		if !ok {
+			continue
+		}
+
+		id64, err := strconv.ParseUint(steamID, 10, 64)
+		if err != nil || id64 < steamID64Base {
+			continue
+		}
+
+		users = append(users, SteamUser{
+			AccountID:   strconv.FormatUint(id64-steamID64Base, 10),
+			AccountName: user.str("AccountName"),
+			MostRecent:  user.str("MostRecent") == "1",
+		})
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].AccountID < users[j].AccountID
+	})
+
+	return users
+}
+
+// variable returns the value of a Steam variable used in rules, and tells if the name was one:
+//
+//   - STEAM_ROOT is the Steam installation folder
+//   - STEAM_LIBRARY:<appid> is the library folder the game is installed in
+//   - STEAM_USERID is the account ID used in userdata/<id>, or * if several accounts have logged in
+func (s *SteamInfo) variable(name string) (string, bool) {
+	switch {
+	case name == "STEAM_ROOT":
+		return s.Root, true
+
+	case name == "STEAM_USERID":
+		if len(s.Users) == 1 {
+			return s.Users[0].AccountID, true
+		}
+		return "*", true
+
+	case strings.HasPrefix(name, "STEAM_LIBRARY:"):
+		appID := strings.TrimPrefix(name, "STEAM_LIBRARY:")
+		if app, ok := s.Apps[appID]; ok {
+			return app.Library, true
+		}
+
+		// Not installed, the main library is the best guess
+		return s.Root, true
+	}
+
+	return "", false
+}
+
+// getSteam returns what we know of the Steam installation, looking for it if we haven't yet
+func (a *App) getSteam() *SteamInfo {
+	if a.steam == nil {
+		a.steam = a.findSteam()
+	}
+	return a.steam
+}
+
+// pollSteamApps asks Steam which games it is running, if any of the rules care
+func (a *App) pollSteamApps() {
+	needed := false
+	for _, rule := range a.Rules {
+		if rule.Platform.SteamAppID != "" {
+			needed = true
+			break
+		}
+	}
+
+	running := map[string]bool{}
+	if needed {
+		appIDs, err := getRunningSteamApps()
+		if err != nil {
+			a.ReportError(err)
+		}
+
+		for _, appID := range appIDs {
+			running[appID] = true
+		}
+	}
+
+	a.runningSteamApps = running
+}
+
+// findSteamGame checks if Steam says the game is running, e.g. when it's started via a custom launcher
+// the executable patterns don't match
+func (a *App) findSteamGame(rule ActiveRule) (processMatcher, ProcessInfo, bool) {
+	appID := rule.Platform.SteamAppID
+	if appID == "" || !a.runningSteamApps[appID] {
+		return processMatcher{}, ProcessInfo{}, false
+	}
+
+	proc := ProcessInfo{Name: appID}
+	if app, ok := a.getSteam().Apps[appID]; ok {
+		proc.Name = app.Name
+		proc.Exe = filepath.Join(app.Library, "steamapps", "common", app.InstallDir)
+	}
+
+	return processMatcher{kind: matchSteamApp, pattern: appID}, proc, true
+}
+
+// runningSteamAppsFromVDF reads the running apps from Steam's registry.vdf
+func runningSteamAppsFromVDF(registryFile string) ([]string, error) {
+	vdf, err := readVDF(registryFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	steam := vdf.node("Registry").node("HKCU").node("Software").node("Valve").node("Steam")
+
+	appIDs := []string{}
+	if appID := steam.str("RunningAppID"); appID != "" && appID != "0" {
+		appIDs = append(appIDs, appID)
+	}
+
+	for appID, value := range steam.node("apps") {
+		app, ok := value.(vdfNode)
+		if ok && app.str("Running") == "1" {
+			appIDs = append(appIDs, appID)
+		}
+	}
+
+	return appIDs, nil
+}
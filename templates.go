@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Template parameters are used in rules as ${param.name}
+var paramVariableRegexp = regexp.MustCompile(`\$\{param\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func (a *App) getTemplatesPath() string {
+	return filepath.Join(a.getRulesPath(), "templates")
+}
+
+// findRuleTemplate finds the file a rule extends, either a template or another rule
+func (a *App) findRuleTemplate(name string) (string, error) {
+	candidates := []string{
+		filepath.Join(a.getTemplatesPath(), fmt.Sprintf("%s.yaml", name)),
+		filepath.Join(a.getRulesPath(), fmt.Sprintf("%s.yaml", name)),
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find template %s", name)
+}
+
+// readRuleDocument reads the rule file, with everything it extends merged in
+func (a *App) readRuleDocument(rulesFile string, seen map[string]bool) (map[string]interface{}, error) {
+	seen[rulesFile] = true
+
+	contents, err := os.ReadFile(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	err = yaml.Unmarshal(contents, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	extends, ok := doc["extends"].(string)
+	if !ok || extends == "" {
+		return doc, nil
+	}
+	delete(doc, "extends")
+
+	templateFile, err := a.findRuleTemplate(extends)
+	if err != nil {
+		return nil, err
+	}
+
+	if seen[templateFile] {
+		return nil, fmt.Errorf("%s extends itself via %s", rulesFile, templateFile)
+	}
+
+	template, err := a.readRuleDocument(templateFile, seen)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template %s: %w", templateFile, err)
+	}
+
+	return mergeRuleDocuments(template, doc), nil
+}
+
+// mergeRuleDocuments merges the rule into the template it extends. Mappings such as platforms and
+// params are merged key by key, anything else the rule sets replaces what the template has.
+func mergeRuleDocuments(template map[string]interface{}, rule map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range template {
+		merged[key] = value
+	}
+
+	for key, value := range rule {
+		templateMap, templateIsMap := merged[key].(map[string]interface{})
+		ruleMap, ruleIsMap := value.(map[string]interface{})
+		if templateIsMap && ruleIsMap {
+			merged[key] = mergeRuleDocuments(templateMap, ruleMap)
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// applyRuleParams replaces the ${param.name} variables everywhere in the rule
+func applyRuleParams(doc map[string]interface{}) (map[string]interface{}, error) {
+	params := map[string]string{}
+	if rawParams, ok := doc["params"].(map[string]interface{}); ok {
+		for name, value := range rawParams {
+			params[name] = fmt.Sprint(value)
+		}
+	}
+
+	// Parameters can default to other parameters, e.g. exe: ${param.product}
+	for range params {
+		for name, value := range params {
+			params[name] = paramVariableRegexp.ReplaceAllStringFunc(value, func(variable string) string {
+				if param, ok := params[paramVariableRegexp.FindStringSubmatch(variable)[1]]; ok {
+					return param
+				}
+				return variable
+			})
+		}
+	}
+
+	// Anything left over refers back to itself, e.g. a: ${param.b} and b: ${param.a}
+	for name, value := range params {
+		for _, match := range paramVariableRegexp.FindAllStringSubmatch(value, -1) {
+			if _, ok := params[match[1]]; ok {
+				return nil, fmt.Errorf("parameter %s refers to itself via %s", name, match[1])
+			}
+		}
+	}
+
+	var missing error
+	var replace func(value interface{}) interface{}
+	replace = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return paramVariableRegexp.ReplaceAllStringFunc(v, func(variable string) string {
+				name := paramVariableRegexp.FindStringSubmatch(variable)[1]
+				param, ok := params[name]
+				if !ok && missing == nil {
+					missing = fmt.Errorf("parameter %s is not set", name)
+				}
+				return param
+			})
+
+		case []interface{}:
+			result := []interface{}{}
+			for _, item := range v {
+				result = append(result, replace(item))
+			}
+			return result
+
+		case map[string]interface{}:
+			result := map[string]interface{}{}
+			for key, item := range v {
+				result[key] = replace(item)
+			}
+			return result
+		}
+
+		return value
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range doc {
+		if key != "params" {
+			result[key] = replace(value)
+		}
+	}
+	result["params"] = params
+
+	return result, missing
+}
+
+// parseRuleFile reads the rule from the file, resolving the templates it extends
+func (a *App) parseRuleFile(rulesFile string, rule *Rule) error {
+	doc, err := a.readRuleDocument(rulesFile, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	doc, err = applyRuleParams(doc)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(data, rule)
+}
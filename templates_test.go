@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRuleFile(t *testing.T) {
+	templates := map[string]string{
+		"templates/unity.yaml": `
+params:
+  exe: ${param.product}
+platforms:
+  linux:
+    executable: "*/${param.exe}.x86_64"
+    savegames:
+      - ${HOME}/.config/unity3d/${param.company}/${param.product}/*
+`,
+		"templates/cycle-a.yaml": "extends: cycle-b\n",
+		"templates/cycle-b.yaml": "extends: cycle-a\n",
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			name: "template with params",
+			rule: `
+name: Some Game
+extends: unity
+params:
+  company: Some Company
+  product: SomeGame
+`,
+			want: Rule{
+				Name: "Some Game",
+				Platforms: map[string]RulePlatform{
+					"linux": {
+						Executable: "*/SomeGame.x86_64",
+						Savegames:  []string{"${HOME}/.config/unity3d/Some Company/SomeGame/*"},
+					},
+				},
+			},
+		},
+		{
+			name: "rule overrides the template",
+			rule: `
+name: Some Game
+extends: unity
+params:
+  company: Some Company
+  product: SomeGame
+  exe: Game
+platforms:
+  linux:
+    savegames:
+      - ${HOME}/Saves/*
+`,
+			want: Rule{
+				Name: "Some Game",
+				Platforms: map[string]RulePlatform{
+					"linux": {
+						Executable: "*/Game.x86_64",
+						Savegames:  []string{"${HOME}/Saves/*"},
+					},
+				},
+			},
+		},
+		{
+			name: "extends another rule",
+			rule: `
+name: Some Game Demo
+extends: game
+params:
+  product: SomeGameDemo
+`,
+			want: Rule{
+				Name: "Some Game Demo",
+				Platforms: map[string]RulePlatform{
+					"linux": {
+						Executable: "*/SomeGameDemo.x86_64",
+						Savegames:  []string{"${HOME}/.config/unity3d/Some Company/SomeGameDemo/*"},
+					},
+				},
+			},
+		},
+		{name: "missing template", rule: "name: Some Game\nextends: unreal\n", wantErr: true},
+		{name: "missing param", rule: "name: Some Game\nextends: unity\nparams:\n  company: Some Company\n", wantErr: true},
+		{name: "extends itself", rule: "name: Some Game\nextends: test\n", wantErr: true},
+		{name: "template cycle", rule: "name: Some Game\nextends: cycle-a\n", wantErr: true},
+		{
+			name:    "param cycle",
+			rule:    "name: Some Game\nextends: unity\nparams:\n  company: ${param.product}\n  product: ${param.company}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newTestApp(t)
+			for name, contents := range templates {
+				writeTestFile(t, filepath.Join(a.getRulesPath(), filepath.FromSlash(name)), contents)
+			}
+			writeTestFile(t, filepath.Join(a.getRulesPath(), "game.yaml"), `
+name: Some Game
+extends: unity
+params:
+  company: Some Company
+  product: SomeGame
+`)
+
+			rulesFile := filepath.Join(a.getRulesPath(), "test.yaml")
+			writeTestFile(t, rulesFile, test.rule)
+
+			rule := Rule{}
+			err := a.parseRuleFile(rulesFile, &rule)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if rule.Name != test.want.Name || !reflect.DeepEqual(rule.Platforms, test.want.Platforms) {
+				t.Fatalf("got %+v, want %+v", rule, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Savegames larger than this are not scanned for embedded images
+const maxThumbnailScanBytes = 64 * 1024 * 1024
+
+// Images smaller than this are likely icons or such, not screenshots
+const minThumbnailBytes = 1024
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	jpegStart    = []byte{0xff, 0xd8, 0xff}
+)
+
+// backupThumbnail stores a thumbnail for the backup in a sidecar file, and returns the sidecar
+// filename or an empty string if no thumbnail could be found
+func (a *App) backupThumbnail(rule ActiveRule, source string, backupDst string, sidecarBase string) string {
+	var data []byte
+	var ext string
+
+	// Prefer a separate thumbnail file if the rule knows of one
+	thumbnailPath := findThumbnailFile(rule.thumbnailTemplates, source)
+	if thumbnailPath != "" {
+		contents, err := os.ReadFile(thumbnailPath)
+		if err != nil {
+			a.ReportError(err)
+		} else {
+			data = contents
+			ext = strings.ToLower(filepath.Ext(thumbnailPath))
+		}
+	}
+
+	if data == nil {
+		data, ext = extractThumbnail(backupDst)
+	}
+
+	if data == nil {
+		return ""
+	}
+
+	sidecarFilename := sidecarBase + ".baacup" + ext
+	err := os.WriteFile(filepath.Join(filepath.Dir(backupDst), sidecarFilename), data, 0o600)
+	if err != nil {
+		a.ReportError(err)
+		return ""
+	}
+
+	return sidecarFilename
+}
+
+// thumbnailPatterns expands the thumbnail pattern of the rule like the savegame patterns. Relative
+// patterns are relative to the savegame, so under Wine they don't depend on the prefixes.
+func (a *App) thumbnailPatterns(rule ActiveRule, winePrefixes []WinePrefix) []string {
+	pattern := rule.Platform.Thumbnail
+	if pattern == "" {
+		return []string{}
+	}
+
+	if rule.ViaWine && !strings.Contains(pattern, "${") {
+		return []string{caseInsensitivePattern(windowsToSlashes(pattern))}
+	}
+
+	patterns := []string{}
+	seen := map[string]bool{}
+	for _, expanded := range a.expandFilePatterns(rule, []string{pattern}, winePrefixes) {
+		if !seen[expanded] {
+			seen[expanded] = true
+			patterns = append(patterns, expanded)
+		}
+	}
+
+	return patterns
+}
+
+// findThumbnailFile resolves the rule's thumbnail patterns for a savegame, where {name} is the
+// savegame filename without extension and {filename} is the full filename. Under Wine, thumbnails
+// in the same prefix as the savegame are preferred.
+func findThumbnailFile(patterns []string, source string) string {
+	filename := filepath.Base(source)
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	found := ""
+	for _, pattern := range patterns {
+		pattern = strings.ReplaceAll(pattern, "{filename}", filename)
+		pattern = strings.ReplaceAll(pattern, "{name}", name)
+
+		// Relative patterns are relative to the savegame
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(source), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range matches {
+			if wineDrive(match) == wineDrive(source) {
+				return match
+			}
+			if found == "" {
+				found = match
+			}
+		}
+	}
+
+	return found
+}
+
+// extractThumbnail looks for an image embedded in the savegame
+func extractThumbnail(filePath string) ([]byte, string) {
+	stat, err := os.Stat(filePath)
+	if err != nil || stat.Size() > maxThumbnailScanBytes {
+		return nil, ""
+	}
+
+	// Ren'Py and some others store the screenshot in a zip archive
+	if data := extractZipThumbnail(filePath); data != nil {
+		return data, ".png"
+	}
+
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, ""
+	}
+
+	if data := findEmbeddedPNG(contents); data != nil {
+		return data, ".png"
+	}
+
+	if data := findEmbeddedJPEG(contents); data != nil {
+		return data, ".jpg"
+	}
+
+	return nil, ""
+}
+
+func extractZipThumbnail(filePath string) []byte {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = archive.Close()
+	}()
+
+	for _, file := range archive.File {
+		if strings.ToLower(file.Name) != "screenshot.png" {
+			continue
+		}
+
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+
+	return nil
+}
+
+// findEmbeddedPNG finds the first complete PNG image in the data by walking its chunks
+func findEmbeddedPNG(data []byte) []byte {
+	offset := 0
+	for {
+		start := bytes.Index(data[offset:], pngSignature)
+		if start < 0 {
+			return nil
+		}
+		start += offset
+
+		pos := start + len(pngSignature)
+		for pos+12 <= len(data) {
+			length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+			chunkType := string(data[pos+4 : pos+8])
+
+			// Length, type, data, and CRC
+			end := pos + 12 + length
+			if length < 0 || end > len(data) {
+				break
+			}
+
+			pos = end
+			if chunkType == "IEND" {
+				if end-start >= minThumbnailBytes {
+					return data[start:end]
+				}
+				break
+			}
+		}
+
+		offset = start + 1
+	}
+}
+
+// findEmbeddedJPEG finds the first complete JPEG image in the data by walking its segments
+func findEmbeddedJPEG(data []byte) []byte {
+	offset := 0
+	for {
+		start := bytes.Index(data[offset:], jpegStart)
+		if start < 0 {
+			return nil
+		}
+		start += offset
+
+		end := jpegEnd(data, start+2)
+		if end > 0 && end-start >= minThumbnailBytes {
+			return data[start:end]
+		}
+
+		offset = start + 1
+	}
+}
+
+// jpegEnd returns the position right after the end of image marker, or -1 if the data is invalid
+func jpegEnd(data []byte, pos int) int {
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return -1
+		}
+
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		pos += 2 + length
+
+		// Start of scan is followed by entropy coded data until the next marker
+		if marker == 0xda {
+			for pos+1 < len(data) {
+				if data[pos] == 0xff {
+					next := data[pos+1]
+					if next == 0xd9 {
+						return pos + 2
+					}
+
+					// Stuffed zero bytes and restart markers are part of the data
+					if next == 0x00 || (next >= 0xd0 && next <= 0xd7) {
+						pos += 2
+						continue
+					}
+
+					// Progressive images have multiple scans
+					break
+				}
+				pos++
+			}
+		}
+	}
+
+	return -1
+}
+
+// GetBackupThumbnail returns the thumbnail of a backup as a data URL, or an empty string if it has none
+func (a *App) GetBackupThumbnail(ruleFilename string, filename string) string {
+	thumbnail := ""
+	a.onMonitor(func() {
+		thumbnail = a.backupThumbnailURL(ruleFilename, filename)
+	})
+	return thumbnail
+}
+
+func (a *App) backupThumbnailURL(ruleFilename string, filename string) string {
+	for _, meta := range a.Backups[ruleFilename] {
+		if meta.Filename != filename || meta.Thumbnail == "" {
+			continue
+		}
+
+		thumbnailPath := filepath.Join(a.getBackupsPath(), ruleFilename, meta.Thumbnail)
+		data, err := os.ReadFile(thumbnailPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				a.ReportError(err)
+			}
+			return ""
+		}
+
+		mimeType := mime.TypeByExtension(filepath.Ext(meta.Thumbnail))
+		if mimeType == "" {
+			mimeType = "image/png"
+		}
+
+		return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+	}
+
+	return ""
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWineThumbnail(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Wine prefixes are only used on Linux")
+	}
+
+	a := newTestApp(t)
+	prefixes := []WinePrefix{
+		{Path: filepath.Join(t.TempDir(), "pfx"), User: "steamuser"},
+		{Path: filepath.Join(t.TempDir(), "wine"), User: "user"},
+	}
+	for _, prefix := range prefixes {
+		saves := filepath.Join(prefix.Path, "drive_c", "users", prefix.User, "Saved Games", "Game")
+		writeTestFile(t, filepath.Join(saves, "slot1.sav"), "save")
+		writeTestFile(t, filepath.Join(saves, "Thumbs", "slot1.png"), "thumbnail")
+	}
+
+	tests := []struct {
+		name      string
+		thumbnail string
+	}{
+		{name: "variable", thumbnail: `${USERPROFILE}\Saved Games\Game\thumbs\{name}.png`},
+		{name: "relative", thumbnail: `THUMBS\{name}.PNG`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := ActiveRule{
+				RuleFilename: "game",
+				ViaWine:      true,
+				Platform:     RulePlatform{Thumbnail: test.thumbnail},
+			}
+			patterns := a.thumbnailPatterns(rule, prefixes)
+
+			for _, prefix := range prefixes {
+				saves := filepath.Join(prefix.Path, "drive_c", "users", prefix.User, "Saved Games", "Game")
+				got := findThumbnailFile(patterns, filepath.Join(saves, "slot1.sav"))
+				if want := filepath.Join(saves, "Thumbs", "slot1.png"); got != want {
+					t.Fatalf("found %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
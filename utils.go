@@ -3,8 +3,11 @@
 package main
 
 import (
+	"bufio"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 func getPlatform() string {
@@ -20,3 +23,108 @@ func getBasePath() string {
 	// Darwin, Linux, other *nix
 	return os.ExpandEnv("$HOME/Baacup")
 }
+
+// getSteamRoots lists the usual places for Steam installations
+func getSteamRoots() []string {
+	home := os.Getenv("HOME")
+	if runtime.GOOS == "darwin" {
+		return []string{
+			filepath.Join(home, "Library", "Application Support", "Steam"),
+		}
+	}
+
+	return []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+	}
+}
+
+// getRunningSteamApps returns the app IDs of the games Steam is running
+func getRunningSteamApps() ([]string, error) {
+	home := os.Getenv("HOME")
+	registryFiles := []string{
+		filepath.Join(home, ".steam", "registry.vdf"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".steam", "registry.vdf"),
+	}
+	if runtime.GOOS == "darwin" {
+		registryFiles = []string{
+			filepath.Join(home, "Library", "Application Support", "Steam", "registry.vdf"),
+		}
+	}
+
+	appIDs := []string{}
+	for _, registryFile := range registryFiles {
+		if _, err := os.Stat(registryFile); err != nil {
+			continue
+		}
+
+		running, err := runningSteamAppsFromVDF(registryFile)
+		if err != nil {
+			return appIDs, err
+		}
+		appIDs = append(appIDs, running...)
+	}
+
+	return appIDs, nil
+}
+
+// getKnownFolder returns the user's folders for the portable rule variables, with the XDG base
+// directories defaulting to the ones in the spec
+func getKnownFolder(name string) (string, bool) {
+	home := os.Getenv("HOME")
+	switch name {
+	case "XDG_DATA_HOME":
+		return xdgDir(name, filepath.Join(".local", "share")), true
+	case "XDG_CONFIG_HOME":
+		return xdgDir(name, ".config"), true
+	case "XDG_STATE_HOME":
+		return xdgDir(name, filepath.Join(".local", "state")), true
+	case "XDG_CACHE_HOME":
+		return xdgDir(name, ".cache"), true
+	case "DOCUMENTS":
+		return getDocumentsDir(), true
+	case "SAVED_GAMES":
+		// Where native games usually keep their saves
+		if runtime.GOOS == "darwin" {
+			return filepath.Join(home, "Library", "Application Support"), true
+		}
+		return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), true
+	}
+
+	return "", false
+}
+
+// getDocumentsDir returns the user's documents folder, which can be renamed e.g. due to localization
+func getDocumentsDir() string {
+	home := os.Getenv("HOME")
+	documents := filepath.Join(home, "Documents")
+	if runtime.GOOS == "darwin" {
+		return documents
+	}
+
+	f, err := os.Open(filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "user-dirs.dirs"))
+	if err != nil {
+		return documents
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// Lines look like XDG_DOCUMENTS_DIR="$HOME/Documents"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "XDG_DOCUMENTS_DIR=") {
+			continue
+		}
+
+		value := strings.Trim(strings.TrimPrefix(line, "XDG_DOCUMENTS_DIR="), `"`)
+		value = strings.Replace(value, "$HOME", home, 1)
+		if filepath.IsAbs(value) {
+			return value
+		}
+	}
+
+	return documents
+}
@@ -5,8 +5,10 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 func getPlatform() string {
@@ -24,3 +26,56 @@ func getBasePath() string {
 
 	return filepath.Join(myDocuments, "Baacup")
 }
+
+// getSteamRoots lists the places for Steam installations, the one from the registry first
+func getSteamRoots() []string {
+	roots := []string{}
+
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Valve\Steam`, registry.QUERY_VALUE)
+	if err == nil {
+		defer key.Close()
+		steamPath, _, err := key.GetStringValue("SteamPath")
+		if err == nil {
+			roots = append(roots, filepath.FromSlash(steamPath))
+		}
+	}
+
+	return append(roots, filepath.Join(os.Getenv("ProgramFiles(x86)"), "Steam"))
+}
+
+// getRunningSteamApps returns the app IDs of the games Steam is running
+func getRunningSteamApps() ([]string, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Valve\Steam`, registry.QUERY_VALUE)
+	if err != nil {
+		// Steam is not installed
+		return []string{}, nil
+	}
+	defer key.Close()
+
+	appID, _, err := key.GetIntegerValue("RunningAppID")
+	if err != nil || appID == 0 {
+		return []string{}, nil
+	}
+
+	return []string{strconv.FormatUint(appID, 10)}, nil
+}
+
+// getKnownFolder returns the user's folders for the portable rule variables
+func getKnownFolder(name string) (string, bool) {
+	var folderID *windows.KNOWNFOLDERID
+	switch name {
+	case "DOCUMENTS":
+		folderID = windows.FOLDERID_Documents
+	case "SAVED_GAMES":
+		folderID = windows.FOLDERID_SavedGames
+	default:
+		return "", false
+	}
+
+	folder, err := windows.KnownFolderPath(folderID, 0)
+	if err != nil {
+		return "", false
+	}
+
+	return folder, true
+}
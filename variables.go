@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// errNotInstalled is returned for variables that point to a game or launcher that isn't installed,
+// which is normal and not worth reporting
+var errNotInstalled = errors.New("not installed")
+
+// variableLookup returns the value of a variable used in rules
+type variableLookup func(name string) (string, error)
+
+// xdgDir returns the XDG base directory from the environment, or its default under $HOME
+func xdgDir(envName string, defaultPath string) string {
+	if dir := os.Getenv(envName); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("HOME"), defaultPath)
+}
+
+// lookupRuleVariable returns the value of a variable used in the rules, e.g. ${HOME}, ${DOCUMENTS},
+// or ${STEAM_ROOT}. Environment variables that are unset or empty are errors, instead of silently
+// turning e.g. ${XDG_DATA_HOME}/Game into /Game.
+func (a *App) lookupRuleVariable(name string) (string, error) {
+	if strings.HasPrefix(name, "match.") || name == "GAME_DIR" {
+		// Filled in once the game is detected
+		return fmt.Sprintf("${%s}", name), nil
+	}
+
+	value, ok := a.getSteam().variable(name)
+	if !ok {
+		value, ok = a.launcherVariable(name)
+	}
+	if ok {
+		if value == "" {
+			return "", errNotInstalled
+		}
+		return value, nil
+	}
+
+	if value, ok := getKnownFolder(name); ok {
+		return value, nil
+	}
+
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+
+	return "", fmt.Errorf("${%s} is not set", name)
+}
+
+// ruleVariable is a variable in a rule pattern, e.g. $NAME, ${NAME}, or ${NAME:-default}
+type ruleVariable struct {
+	start int
+	end   int
+	expr  string
+}
+
+// isVariableChar tells if the character can be part of a $NAME variable
+func isVariableChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (!first && c >= '0' && c <= '9')
+}
+
+// findVariables finds the variables in the pattern. Unlike os.Expand, the braces are matched so
+// defaults can contain other variables, e.g. ${XDG_DATA_HOME:-${HOME}/.local/share}.
+func findVariables(pattern string) ([]ruleVariable, error) {
+	variables := []ruleVariable{}
+	for i := 0; i < len(pattern)-1; i++ {
+		if pattern[i] != '$' {
+			continue
+		}
+
+		if pattern[i+1] == '{' {
+			end := -1
+			depth := 0
+			for j := i + 1; j < len(pattern) && end < 0; j++ {
+				switch pattern[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+					if depth == 0 {
+						end = j
+					}
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ${ in %s", pattern)
+			}
+
+			variables = append(variables, ruleVariable{start: i, end: end + 1, expr: pattern[i+2 : end]})
+			i = end
+			continue
+		}
+
+		end := i + 1
+		for end < len(pattern) && isVariableChar(pattern[end], end == i+1) {
+			end++
+		}
+		if end > i+1 {
+			variables = append(variables, ruleVariable{start: i, end: end, expr: pattern[i+1 : end]})
+			i = end - 1
+		}
+	}
+
+	return variables, nil
+}
+
+// expandVariables replaces the variables in the pattern, with ${NAME:-default} giving a default for
+// variables that can't be resolved. The default can use other variables, e.g. ${GAME_SAVES:-$HOME}.
+func expandVariables(pattern string, lookup variableLookup) (string, error) {
+	variables, err := findVariables(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	result := strings.Builder{}
+	last := 0
+	for _, variable := range variables {
+		result.WriteString(pattern[last:variable.start])
+
+		name, defaultValue, hasDefault := strings.Cut(variable.expr, ":-")
+		value, err := lookup(name)
+		if err != nil && hasDefault {
+			value, err = expandVariables(defaultValue, lookup)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		result.WriteString(value)
+		last = variable.end
+	}
+	result.WriteString(pattern[last:])
+
+	return result.String(), nil
+}
+
+// expandRuleVariables replaces the variables in the rule pattern
+func (a *App) expandRuleVariables(pattern string) (string, error) {
+	return expandVariables(pattern, a.lookupRuleVariable)
+}
+
+// expandProcessPattern replaces the variables in a pattern for matching processes, quoting the values
+// so e.g. the backslashes in Windows paths aren't taken as escapes
+func (a *App) expandProcessPattern(pattern string) (string, error) {
+	return expandVariables(pattern, func(name string) (string, error) {
+		value, err := a.lookupRuleVariable(name)
+		if err != nil || value == "*" {
+			// Wildcard for several Steam accounts
+			return value, err
+		}
+		return glob.QuoteMeta(value), nil
+	})
+}
+
+// reportPatternError tells the user about a pattern in a rule we couldn't resolve, unless it's just
+// for a game that isn't installed
+func (a *App) reportPatternError(rule ActiveRule, pattern string, err error) {
+	if errors.Is(err, errNotInstalled) {
+		return
+	}
+	a.ReportError(fmt.Errorf("failed to resolve pattern %s from %s.yaml: %w", pattern, rule.RuleFilename, err))
+}
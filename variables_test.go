@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	values := map[string]string{
+		"HOME":          "/home/user",
+		"XDG_DATA_HOME": "/data",
+		"GAME":          "Game",
+	}
+	lookup := func(name string) (string, error) {
+		if value, ok := values[name]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("${%s} is not set", name)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "no variables", pattern: "/saves/*.sav", want: "/saves/*.sav"},
+		{name: "braces", pattern: "${HOME}/.local/share/${GAME}", want: "/home/user/.local/share/Game"},
+		{name: "bare", pattern: "$HOME/$GAME-1", want: "/home/user/Game-1"},
+		{name: "dollar without name", pattern: "/saves/$/$1/a$", want: "/saves/$/$1/a$"},
+		{name: "unknown", pattern: "${SAVES}/x", wantErr: true},
+		{name: "set with default", pattern: "${XDG_DATA_HOME:-/fallback}/Game", want: "/data/Game"},
+		{name: "unset with default", pattern: "${SAVES:-/fallback}/Game", want: "/fallback/Game"},
+		{name: "nested default", pattern: "${SAVES:-${HOME}/.local/share}/Game", want: "/home/user/.local/share/Game"},
+		{name: "nested default twice", pattern: "${SAVES:-${DATA:-${HOME}/data}}/x", want: "/home/user/data/x"},
+		{name: "nested bare default", pattern: "${SAVES:-$HOME}/Game", want: "/home/user/Game"},
+		{name: "unknown in default", pattern: "${SAVES:-${DATA}}/x", wantErr: true},
+		{name: "braces after default", pattern: "${SAVES:-/a}/{name}.png", want: "/a/{name}.png"},
+		{name: "unterminated", pattern: "${HOME/x", wantErr: true},
+		{name: "unterminated nested", pattern: "${SAVES:-${HOME}/x", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandVariables(test.pattern, lookup)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// vdfNode is a parsed Valve KeyValues (VDF) object, with lower case keys as Steam treats them
+// case-insensitively. Values are either strings or nested vdfNodes.
+type vdfNode map[string]interface{}
+
+// str returns the string value of the key, or an empty string
+func (n vdfNode) str(key string) string {
+	value, _ := n[strings.ToLower(key)].(string)
+	return value
+}
+
+// node returns the nested object of the key, or an empty one
+func (n vdfNode) node(key string) vdfNode {
+	value, ok := n[strings.ToLower(key)].(vdfNode)
+	if !ok {
+		return vdfNode{}
+	}
+	return value
+}
+
+func readVDF(filePath string) (vdfNode, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := parseVDF(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+
+	return node, nil
+}
+
+func parseVDF(contents string) (vdfNode, error) {
+	tokens, err := tokenizeVDF(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+	root, err := parseVDFObject(tokens, &pos, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+type vdfToken struct {
+	value  string
+	quoted bool
+}
+
+func parseVDFObject(tokens []vdfToken, pos *int, nested bool) (vdfNode, error) {
+	node := vdfNode{}
+	for *pos < len(tokens) {
+		key := tokens[*pos]
+		*pos++
+
+		if key.value == "}" && !key.quoted {
+			if !nested {
+				return nil, fmt.Errorf("unexpected }")
+			}
+			return node, nil
+		}
+
+		if key.value == "{" && !key.quoted {
+			return nil, fmt.Errorf("unexpected {")
+		}
+
+		if *pos >= len(tokens) || (tokens[*pos].value == "}" && !tokens[*pos].quoted) {
+			return nil, fmt.Errorf("missing value for %s", key.value)
+		}
+
+		value := tokens[*pos]
+		*pos++
+
+		if value.value == "{" && !value.quoted {
+			child, err := parseVDFObject(tokens, pos, true)
+			if err != nil {
+				return nil, err
+			}
+			node[strings.ToLower(key.value)] = child
+			continue
+		}
+
+		node[strings.ToLower(key.value)] = value.value
+	}
+
+	if nested {
+		return nil, fmt.Errorf("missing }")
+	}
+
+	return node, nil
+}
+
+func tokenizeVDF(contents string) ([]vdfToken, error) {
+	tokens := []vdfToken{}
+	runes := []rune(contents)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			continue
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			// Comment until the end of the line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '{' || r == '}':
+			tokens = append(tokens, vdfToken{value: string(r)})
+
+		case r == '"':
+			value := strings.Builder{}
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						value.WriteRune('\n')
+					case 't':
+						value.WriteRune('\t')
+					default:
+						value.WriteRune(runes[i])
+					}
+					continue
+				}
+				value.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, vdfToken{value: value.String(), quoted: true})
+
+		default:
+			// Unquoted values end at whitespace or braces
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\r\n{}\"", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, vdfToken{value: string(runes[start:i])})
+			i--
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVDF(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     vdfNode
+		wantErr  bool
+	}{
+		{name: "empty", contents: "", want: vdfNode{}},
+		{
+			name: "nested",
+			contents: `"LibraryFolders"
+{
+	"0"
+	{
+		"path"		"/home/user/.steam/steam"
+	}
+}`,
+			want: vdfNode{"libraryfolders": vdfNode{"0": vdfNode{"path": "/home/user/.steam/steam"}}},
+		},
+		{
+			name:     "keys are case-insensitive",
+			contents: `"AppState" { "AppID" "228280" }`,
+			want:     vdfNode{"appstate": vdfNode{"appid": "228280"}},
+		},
+		{
+			name:     "escapes",
+			contents: `"path" "C:\\Games\\\"Quoted\"\n"`,
+			want:     vdfNode{"path": "C:\\Games\\\"Quoted\"\n"},
+		},
+		{
+			name:     "unquoted values and comments",
+			contents: "// Steam registry\nRegistry { Running 1 } // trailing",
+			want:     vdfNode{"registry": vdfNode{"running": "1"}},
+		},
+		{
+			name:     "quoted braces are values",
+			contents: `"open" "{" "close" "}"`,
+			want:     vdfNode{"open": "{", "close": "}"},
+		},
+		{name: "unterminated string", contents: `"path" "/home`, wantErr: true},
+		{name: "missing closing brace", contents: `"users" { "1" { "name" "x" }`, wantErr: true},
+		{name: "extra closing brace", contents: `"users" { } }`, wantErr: true},
+		{name: "missing value", contents: `"path"`, wantErr: true},
+		{name: "missing value in object", contents: `"users" { "name" }`, wantErr: true},
+		{name: "object without key", contents: `{ "name" "x" }`, wantErr: true},
+		{name: "empty object without key", contents: `{ }`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseVDF(test.contents)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long files need to stay untouched after a notification before we back them up, so we don't
+// make a backup of a half-written savegame
+const watchSettleTime = 300 * time.Millisecond
+
+func (a *App) startWatcher() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		a.ReportError(fmt.Errorf("filesystem notifications are not available, polling for changes instead: %w", err))
+		return
+	}
+
+	a.watcher = watcher
+}
+
+func (a *App) stopWatcher() {
+	if a.watcher == nil {
+		return
+	}
+
+	err := a.watcher.Close()
+	if err != nil {
+		a.ReportError(err)
+	}
+}
+
+// watchDirs finds the existing directories that can contain files matching the pattern
+func watchDirs(pattern string) []string {
+	dirs, err := filepath.Glob(filepath.Dir(pattern))
+	if err != nil {
+		return []string{}
+	}
+
+	return dirs
+}
+
+// updateWatches starts watching the directories of our active monitors, and stops watching the
+// ones no longer needed. Monitors we can't watch get polled instead.
+func (a *App) updateWatches() {
+	if a.watcher == nil {
+		a.polledMonitors = a.ActiveMonitors
+		return
+	}
+
+	wanted := map[string]bool{}
+	polled := []Monitor{}
+	for _, monitor := range a.ActiveMonitors {
+		dirs := watchDirs(monitor.Path)
+		if len(dirs) == 0 {
+			// The game has probably not created the folder yet
+			polled = append(polled, monitor)
+			continue
+		}
+
+		watched := true
+		for _, dir := range dirs {
+			wanted[dir] = true
+			if a.watchedDirs[dir] {
+				continue
+			}
+
+			err := a.watcher.Add(dir)
+			if err != nil {
+				a.AddEvent(fmt.Sprintf("Could not watch %s for changes, polling instead: %s", dir, err))
+				watched = false
+				continue
+			}
+			a.watchedDirs[dir] = true
+		}
+
+		if !watched {
+			polled = append(polled, monitor)
+		}
+	}
+
+	for dir := range a.watchedDirs {
+		if !wanted[dir] {
+			// Removing fails if the directory was deleted, which is fine
+			_ = a.watcher.Remove(dir)
+			delete(a.watchedDirs, dir)
+		}
+	}
+
+	a.polledMonitors = polled
+}
+
+func (a *App) handleWatchEvent(event fsnotify.Event) {
+	if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+		a.pendingChanges[event.Name] = true
+	}
+}
+
+// checkPendingChanges backs up the changed files once they've settled
+func (a *App) checkPendingChanges() {
+	for changed := range a.pendingChanges {
+		delete(a.pendingChanges, changed)
+
+		for _, monitor := range a.ActiveMonitors {
+			match, err := filepath.Match(monitor.Path, changed)
+			if err != nil || !match || isExcluded(changed, monitor.Path, monitor.Exclude) || a.isPaused(monitor.RuleFilename) {
+				continue
+			}
+
+			if a.needsBackup(changed, a.Backups[monitor.RuleFilename]) {
+				a.backupFile(monitor.RuleFilename, changed, "")
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// WinePrefix is a Wine or Proton prefix, and the Windows user inside it
+type WinePrefix struct {
+	Path string `json:"path"`
+	User string `json:"user"`
+}
+
+// findWinePrefixes finds the Wine and Proton prefixes on this machine
+func findWinePrefixes(steam *SteamInfo, games []InstalledGame) []WinePrefix {
+	candidates := []string{}
+	if winePrefix := os.Getenv("WINEPREFIX"); winePrefix != "" {
+		candidates = append(candidates, winePrefix)
+	}
+	candidates = append(candidates, filepath.Join(os.Getenv("HOME"), ".wine"))
+	candidates = append(candidates, winePrefixCandidates(games)...)
+
+	for _, library := range steam.Libraries {
+		pfxs, err := filepath.Glob(filepath.Join(library, "steamapps", "compatdata", "*", "pfx"))
+		if err == nil {
+			candidates = append(candidates, pfxs...)
+		}
+	}
+
+	return winePrefixUsers(candidates)
+}
+
+// winePrefixUsers finds the users of the existing prefixes, skipping duplicates
+func winePrefixUsers(candidates []string) []WinePrefix {
+	prefixes := []WinePrefix{}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		users, err := os.ReadDir(filepath.Join(resolved, "drive_c", "users"))
+		if err != nil {
+			continue
+		}
+
+		for _, user := range users {
+			if !user.IsDir() || user.Name() == "Public" {
+				continue
+			}
+
+			prefixes = append(prefixes, WinePrefix{
+				Path: resolved,
+				User: user.Name(),
+			})
+		}
+	}
+
+	return prefixes
+}
+
+// variables returns the Windows folders in the prefix, keyed by upper case variable name since
+// Windows environment variables are case-insensitive
+func (p WinePrefix) variables() map[string]string {
+	userProfile := filepath.Join(p.Path, "drive_c", "users", p.User)
+
+	// Older Wine versions use "My Documents"
+	documents := filepath.Join(userProfile, "Documents")
+	if _, err := os.Stat(filepath.Join(userProfile, "My Documents")); err == nil {
+		documents = filepath.Join(userProfile, "My Documents")
+	}
+
+	return map[string]string{
+		"USERPROFILE":  userProfile,
+		"APPDATA":      filepath.Join(userProfile, "AppData", "Roaming"),
+		"LOCALAPPDATA": filepath.Join(userProfile, "AppData", "Local"),
+		"MYDOCUMENTS":  documents,
+		"DOCUMENTS":    documents,
+		"SAVED_GAMES":  filepath.Join(userProfile, "Saved Games"),
+	}
+}
+
+// winePattern turns a Windows savegame pattern into a pattern for the prefix. The Windows part of
+// the pattern is matched case-insensitively, as it would be on Windows. Variables that aren't Windows
+// folders are looked up with lookup.
+func (p WinePrefix) winePattern(pattern string, lookup variableLookup) (string, error) {
+	variables := p.variables()
+	prefixLookup := func(name string) (string, error) {
+		if value, ok := variables[strings.ToUpper(name)]; ok {
+			return value, nil
+		}
+		return lookup(name)
+	}
+
+	found, err := findVariables(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	result := strings.Builder{}
+	last := 0
+	for _, variable := range found {
+		result.WriteString(caseInsensitivePattern(windowsToSlashes(pattern[last:variable.start])))
+
+		value, err := expandVariables(pattern[variable.start:variable.end], prefixLookup)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(value)
+
+		last = variable.end
+	}
+	result.WriteString(caseInsensitivePattern(windowsToSlashes(pattern[last:])))
+
+	return result.String(), nil
+}
+
+// windowsToSlashes turns the backslashes in Windows paths into slashes
+func windowsToSlashes(path string) string {
+	parts := strings.FieldsFunc(path, func(r rune) bool {
+		return r == '\\'
+	})
+
+	joined := strings.Join(parts, "/")
+	if strings.HasPrefix(path, "\\") {
+		joined = "/" + joined
+	}
+	if strings.HasSuffix(path, "\\") {
+		joined += "/"
+	}
+
+	return joined
+}
+
+// wineDrive returns the drive_c folder of the Wine prefix the path is in, or an empty string if it's
+// not in a prefix
+func wineDrive(path string) string {
+	if i := strings.Index(path, "/drive_c/"); i >= 0 {
+		return path[:i+len("/drive_c")]
+	}
+	return ""
+}
+
+// caseInsensitivePattern turns letters into character classes, e.g. "Save" into "[sS][aA][vV][eE]",
+// leaving any character classes and placeholders like {name} already in the pattern alone
+func caseInsensitivePattern(pattern string) string {
+	result := strings.Builder{}
+	inClass := false
+	inPlaceholder := false
+	for _, r := range pattern {
+		switch {
+		case r == '[':
+			inClass = true
+			result.WriteRune(r)
+		case r == ']':
+			inClass = false
+			result.WriteRune(r)
+		case r == '{' && !inClass:
+			inPlaceholder = true
+			result.WriteRune(r)
+		case r == '}' && !inClass:
+			inPlaceholder = false
+			result.WriteRune(r)
+		case !inClass && !inPlaceholder && unicode.IsLetter(r) && unicode.ToLower(r) != unicode.ToUpper(r):
+			result.WriteRune('[')
+			result.WriteRune(unicode.ToLower(r))
+			result.WriteRune(unicode.ToUpper(r))
+			result.WriteRune(']')
+		default:
+			result.WriteRune(r)
+		}
+	}
+
+	return result.String()
+}
+
+// winePatterns maps the Windows pattern into all the prefixes
+func winePatterns(pattern string, prefixes []WinePrefix, lookup variableLookup) ([]string, error) {
+	result := []string{}
+	for _, prefix := range prefixes {
+		winePattern, err := prefix.winePattern(pattern, lookup)
+		if err != nil {
+			return []string{}, err
+		}
+		result = append(result, winePattern)
+	}
+
+	return result, nil
+}
+
+// wineExecutablePattern turns a pattern for a Windows executable into one matching the lower case
+// command line of a Wine process, which contains the Windows or Unix path of the executable
+func wineExecutablePattern(pattern string) []string {
+	// Keep the case of the capture names
+	lower := strings.ToLower(pattern)
+	for _, capture := range captureRegexp.FindAllString(pattern, -1) {
+		lower = strings.Replace(lower, strings.ToLower(capture), capture, 1)
+	}
+
+	return []string{
+		lower + "*",
+		strings.ReplaceAll(lower, `\\`, "/") + "*",
+	}
+}
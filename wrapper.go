@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Events the launch wrapper reports to the running Baacup instance
+const (
+	wrapperEventStarted = "started"
+	wrapperEventStopped = "stopped"
+)
+
+// How long the wrapper waits for the running instance to e.g. finish the pre-launch snapshot
+const wrapperRequestTimeout = 5 * time.Minute
+
+// Header with the token from instance.yaml, so only processes that can read our files can report
+const instanceTokenHeader = "X-Baacup-Token"
+
+// InstanceInfo tells other Baacup processes how to reach the running instance
+type InstanceInfo struct {
+	Port  int    `yaml:"port"`
+	PID   int    `yaml:"pid"`
+	Token string `yaml:"token"`
+}
+
+// wrapperRequest is sent by `baacup run` to the running instance
+type wrapperRequest struct {
+	Event        string   `json:"event"`
+	RuleFilename string   `json:"ruleFilename"`
+	Command      []string `json:"command"`
+	done         chan error
+}
+
+// wrapperResponse is returned by the running instance to `baacup run`
+type wrapperResponse struct {
+	Error string `json:"error"`
+}
+
+func (a *App) getInstancePath() string {
+	return filepath.Join(a.BasePath, "instance.yaml")
+}
+
+// startInstanceServer lets `baacup run` report to this instance
+func (a *App) startInstanceServer() {
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
+	if err != nil {
+		a.ReportError(fmt.Errorf("could not generate a token for baacup run: %w", err))
+		return
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		a.ReportError(fmt.Errorf("could not listen for baacup run: %w", err))
+		return
+	}
+
+	info := InstanceInfo{
+		Port:  listener.Addr().(*net.TCPAddr).Port,
+		PID:   os.Getpid(),
+		Token: hex.EncodeToString(token),
+	}
+
+	data, err := yaml.Marshal(info)
+	if err == nil {
+		err = os.WriteFile(a.getInstancePath(), data, 0o600)
+	}
+	if err != nil {
+		a.ReportError(err)
+		a.ReportError(fmt.Errorf("error writing instance information to %s", a.getInstancePath()))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		// Browsers send an Origin with requests from web pages, `baacup run` never does
+		if r.Method != http.MethodPost || r.Header.Get("Origin") != "" {
+			writeWrapperResponse(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(instanceTokenHeader)), []byte(info.Token)) != 1 {
+			writeWrapperResponse(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+			return
+		}
+
+		a.handleInstanceRun(w, r)
+	})
+
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			a.ReportError(err)
+		}
+	}()
+
+	a.instanceListener = listener
+}
+
+func (a *App) stopInstanceServer() {
+	if a.instanceListener == nil {
+		return
+	}
+
+	_ = a.instanceListener.Close()
+	a.tryDeleteFile(a.getInstancePath())
+}
+
+func (a *App) handleInstanceRun(w http.ResponseWriter, r *http.Request) {
+	req := wrapperRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeWrapperResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The monitor handles the request so we don't step on its toes, but it may be busy or stopped
+	req.done = make(chan error, 1)
+	timeout := time.NewTimer(wrapperRequestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case a.wrapperRequests <- req:
+		select {
+		case err = <-req.done:
+		case <-timeout.C:
+			err = fmt.Errorf("timed out handling %s event", req.Event)
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		}
+
+	case <-a.monitorStopped:
+		err = fmt.Errorf("baacup is shutting down")
+	case <-timeout.C:
+		err = fmt.Errorf("timed out handling %s event", req.Event)
+	case <-r.Context().Done():
+		err = r.Context().Err()
+	}
+
+	if err != nil {
+		writeWrapperResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeWrapperResponse(w, http.StatusOK, nil)
+}
+
+func writeWrapperResponse(w http.ResponseWriter, status int, err error) {
+	resp := wrapperResponse{}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleWrapperRequest keeps games launched via `baacup run` running until the wrapper says they
+// stopped, regardless of what the process list says
+func (a *App) handleWrapperRequest(req wrapperRequest) error {
+	ruleFilename := req.RuleFilename
+	if ruleFilename == "" {
+		var ok bool
+		ruleFilename, ok = a.findRuleForCommand(req.Command)
+		if !ok {
+			return fmt.Errorf("no rule matches %v", req.Command)
+		}
+	}
+
+	if _, ok := a.Rules[ruleFilename]; !ok {
+		return fmt.Errorf("no such rule %s", ruleFilename)
+	}
+
+	switch req.Event {
+	case wrapperEventStarted:
+		a.wrappedRules[ruleFilename] = true
+	case wrapperEventStopped:
+		delete(a.wrappedRules, ruleFilename)
+	default:
+		return fmt.Errorf("unknown event %s", req.Event)
+	}
+
+	a.CheckRules()
+
+	return nil
+}
+
+// findRuleForCommand finds the first rule whose executable matches any part of the command
+func (a *App) findRuleForCommand(command []string) (string, bool) {
+	ruleFilenames := []string{}
+	for ruleFilename, rule := range a.Rules {
+		if !rule.Disabled {
+			ruleFilenames = append(ruleFilenames, ruleFilename)
+		}
+	}
+	sort.Strings(ruleFilenames)
+
+	for _, ruleFilename := range ruleFilenames {
+		if _, _, ok := a.Rules[ruleFilename].matchCommand(command); ok {
+			return ruleFilename, true
+		}
+	}
+
+	return "", false
+}
+
+// matchCommand checks if any part of the command is the game
+func (rule ActiveRule) matchCommand(command []string) (processMatcher, ProcessInfo, bool) {
+	cmdline := strings.Join(command, " ")
+	for _, arg := range command {
+		proc := ProcessInfo{
+			Exe:     arg,
+			Name:    filepath.Base(arg),
+			Cmdline: cmdline,
+		}
+		if matcher, ok := rule.matchProcess(proc); ok {
+			return matcher, proc, true
+		}
+
+		if abs, err := filepath.Abs(arg); err == nil {
+			proc.Exe = abs
+			if matcher, ok := rule.matchProcess(proc); ok {
+				return matcher, proc, true
+			}
+		}
+	}
+
+	return processMatcher{}, ProcessInfo{}, false
+}
+
+// notifyInstance reports the event to the running instance, and tells if there was one
+func (a *App) notifyInstance(req wrapperRequest) (bool, error) {
+	contents, err := os.ReadFile(a.getInstancePath())
+	if err != nil {
+		return false, nil
+	}
+
+	info := InstanceInfo{}
+	err = yaml.Unmarshal(contents, &info)
+	if err != nil {
+		return false, nil
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return false, err
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/run", info.Port)
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(instanceTokenHeader, info.Token)
+
+	client := http.Client{Timeout: wrapperRequestTimeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		// Most likely the instance has crashed and left the file behind
+		return false, nil
+	}
+	defer func() {
+		_ = httpResp.Body.Close()
+	}()
+
+	resp := wrapperResponse{}
+	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	if err != nil {
+		// Someone else is using the port
+		return false, nil
+	}
+
+	if resp.Error != "" {
+		return true, fmt.Errorf("baacup could not handle the game %s: %s", req.Event, resp.Error)
+	}
+
+	return true, nil
+}
+
+// runWrapper implements `baacup run [-rule name] [--] command...`, to be used e.g. as a Steam launch
+// option `baacup run %command%`
+func runWrapper(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	ruleFlag := flags.String("rule", "", "rule to use, e.g. \"Baldurs Gate 2 - Steam\", detected from the command if not given")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: baacup run [-rule name] [--] command [args...]")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	command := flags.Args()
+	if len(command) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	a := newApp()
+	a.headless = true
+
+	// If Baacup is already running, it takes care of the backups
+	reported, err := a.notifyInstance(wrapperRequest{
+		Event:        wrapperEventStarted,
+		RuleFilename: *ruleFlag,
+		Command:      command,
+	})
+	if err != nil {
+		a.ReportError(err)
+	}
+
+	var rule ActiveRule
+	hasRule := false
+	if !reported {
+		rule, hasRule = a.prepareWrappedRule(*ruleFlag, command)
+		if hasRule {
+			a.gameStarted(rule)
+		}
+	}
+
+	exitCode := runChild(command, func() {
+		if hasRule {
+			a.backupChangedFiles(rule.RuleFilename, rule, "")
+		}
+	})
+
+	if reported {
+		_, err = a.notifyInstance(wrapperRequest{
+			Event:        wrapperEventStopped,
+			RuleFilename: *ruleFlag,
+			Command:      command,
+		})
+		if err != nil {
+			a.ReportError(err)
+		}
+	} else if hasRule {
+		a.gameStopped(rule)
+	}
+
+	return exitCode
+}
+
+// prepareWrappedRule loads what we need to back up the game ourselves without the GUI
+func (a *App) prepareWrappedRule(ruleFilename string, command []string) (ActiveRule, bool) {
+	a.ensurePath(a.getBackupsPath())
+	a.ensurePath(a.getRulesPath())
+	a.ensurePath(a.getSessionsPath())
+
+	a.LoadConfig()
+	a.LoadRules()
+
+	if ruleFilename == "" {
+		var ok bool
+		ruleFilename, ok = a.findRuleForCommand(command)
+		if !ok {
+			a.ReportError(fmt.Errorf("no rule matches %v, running without backups", command))
+			return ActiveRule{}, false
+		}
+	}
+
+	rule, ok := a.Rules[ruleFilename]
+	if !ok {
+		a.ReportError(fmt.Errorf("no such rule %s, running without backups", ruleFilename))
+		return ActiveRule{}, false
+	}
+
+	if rule.Disabled {
+		a.ReportError(fmt.Errorf("%s is disabled, running without backups", ruleFilename))
+		return ActiveRule{}, false
+	}
+
+	a.Backups[ruleFilename] = a.findBackupMetadata(ruleFilename)
+	a.Sessions[ruleFilename] = a.loadSessions(ruleFilename)
+
+	if matcher, proc, ok := rule.matchCommand(command); ok {
+		rule.DetectedBy = matcher.String()
+		rule.Captures = matcher.capture(proc)
+
+		if gameDir := detectedGameDir(rule, matcher, proc); gameDir != "" {
+			rule.resolveSavegames(gameDir)
+			a.rememberGameDir(rule)
+		} else {
+			rule.resolveSavegames(rule.GameDir)
+		}
+	} else {
+		rule.DetectedBy = matchWrapper
+	}
+
+	return rule, true
+}
+
+// runChild runs the command until it exits, calling poll every second, and returns its exit code
+func runChild(command []string, poll func()) int {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 127
+	}
+
+	// Pass on signals so the game gets a chance to save before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				return 0
+			}
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.ExitCode()
+			}
+
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+
+		case sig := <-signals:
+			_ = cmd.Process.Signal(sig)
+
+		case <-ticker.C:
+			poll()
+		}
+	}
+}